@@ -0,0 +1,214 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/config"
+	"github.com/nautes-labs/cli/cmd/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ResourceAPIVersion = "nautes.resource.nautes.io/v1alpha1"
+
+	ClusterUsageHost      = "host"
+	ClusterUsageWorker    = "worker"
+	ClusterTypePhysical   = "physical"
+	ClusterTypeVirtual    = "virtual"
+	WorkerTypePipeline    = "pipeline"
+	WorkerTypeDeployment  = "deployment"
+	ClusterKindKubernetes = "kubernetes"
+)
+
+// clusterRegisterOptions holds the flags of the cluster register command.
+type clusterRegisterOptions struct {
+	kubeconfig    string
+	kubeContext   string
+	usage         string
+	clusterType   string
+	workerType    string
+	hostCluster   string
+	httpsNodePort string
+	primaryDomain string
+	output        string
+}
+
+// NewClusterCommand creates the "cluster" command and its subcommands which build clusters from local kubeconfig files.
+func NewClusterCommand(clientOptions *types.ClientOptions) *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "cluster",
+		Short: "Register clusters from local kubeconfig files",
+		Run: func(c *cobra.Command, args []string) {
+			c.HelpFunc()(c, args)
+		},
+	}
+	command.AddCommand(newClusterRegisterCommand(clientOptions))
+	return command
+}
+
+func newClusterRegisterCommand(clientOptions *types.ClientOptions) *cobra.Command {
+	var options clusterRegisterOptions
+	var command = &cobra.Command{
+		Use:   "register name",
+		Short: "Register a cluster from a context of a kubeconfig file",
+		Long: `Register a cluster from a context of a kubeconfig file.
+
+The API server of the cluster is the server of the context, and the kubeconfig of the cluster is reduced to the context
+with its certificates and token embedded. The primary domain defaults to <ip>.nip.io when the server is an IP address.
+With --output yaml the manifest of the cluster is printed instead of being applied, it is the input of apply.`,
+		Example: `nautes cluster register host --kube-context kind-host --usage host
+
+nautes cluster register vcluster-pipeline --kubeconfig ./vcluster.yaml --usage worker --cluster-type virtual \
+  --worker-type pipeline --host-cluster host --https-node-port 31456 -o yaml`,
+		Args: cobra.ExactArgs(1),
+		// Printing the manifest does not talk to the API server.
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			if options.output != "" {
+				return nil
+			}
+			return ResolveClientOptions(c, clientOptions)
+		},
+		Run: func(c *cobra.Command, args []string) {
+			cluster, err := buildCluster(args[0], &options)
+			CheckError(err)
+			switch options.output {
+			case OutputYaml:
+				CheckError(printManifest(cluster))
+			case "":
+				apiServer := formatAPIServer(clientOptions.ServerAddr)
+				_, err = buildResourceAndDo(MethodPost, apiServer, clientOptions.Token, clientOptions.SkipCheck, cluster, os.Stdout)
+				CheckError(err)
+				fmt.Printf("%s '%s' saved successfully.\n", cluster.GetKind(), cluster.Spec.Name)
+			default:
+				CheckError(fmt.Errorf("unknown output format: %s", options.output))
+			}
+		},
+	}
+	command.Flags().StringVar(&options.kubeconfig, "kubeconfig", "", "Path of the kubeconfig file, defaults to the first file of $KUBECONFIG or ~/.kube/config")
+	// The context of the kubeconfig is not named --context, which is the context of the CLI configuration.
+	command.Flags().StringVar(&options.kubeContext, "kube-context", "", "Context of the kubeconfig file, defaults to its current context")
+	command.Flags().StringVar(&options.usage, "usage", "", "Usage of the cluster. One of: host|worker (required)")
+	command.Flags().StringVar(&options.clusterType, "cluster-type", ClusterTypePhysical, "Type of the cluster. One of: physical|virtual")
+	command.Flags().StringVar(&options.workerType, "worker-type", "", "Runtime type of a worker cluster. One of: pipeline|deployment")
+	command.Flags().StringVar(&options.hostCluster, "host-cluster", "", "Host cluster of a virtual cluster")
+	command.Flags().StringVar(&options.httpsNodePort, "https-node-port", "", "Node port of the API server of a virtual cluster on its host cluster")
+	command.Flags().StringVar(&options.primaryDomain, "primary-domain", "", "Primary domain of the cluster, defaults to <ip>.nip.io when the server is an IP address")
+	command.Flags().StringVarP(&options.output, "output", "o", "", "Print the manifest of the cluster instead of applying it. One of: yaml")
+	command.Flags().BoolVarP(&clientOptions.SkipCheck, FlagInsecure, "i", false, "Skipping the compliance check (optional)")
+	CheckError(command.MarkFlagRequired("usage"))
+	return command
+}
+
+// buildCluster builds the cluster from the context of the kubeconfig file and checks it.
+func buildCluster(name string, options *clusterRegisterOptions) (*types.Cluster, error) {
+	path := config.ExpandHome(firstNonEmpty(options.kubeconfig, defaultKubeconfigFile()))
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading kubeconfig: %w", err)
+	}
+	kubeconfigContent, err := minifyKubeconfig(content, options.kubeContext, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	server, err := kubeconfigServer(kubeconfigContent)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := &types.Cluster{
+		APIVersion: ResourceAPIVersion,
+		Kind:       "Cluster",
+		Spec: types.ClusterResponseItem{
+			Name:          name,
+			ApiServer:     server,
+			ClusterKind:   ClusterKindKubernetes,
+			Usage:         options.usage,
+			ClusterType:   options.clusterType,
+			WorkerType:    options.workerType,
+			HostCluster:   options.hostCluster,
+			PrimaryDomain: options.primaryDomain,
+			Kubeconfig:    string(kubeconfigContent),
+			VCluster:      types.VCluster{HTTPSNodePort: options.httpsNodePort},
+		},
+	}
+	var problems []string
+	if cluster.Spec.PrimaryDomain == "" {
+		if serverURL, err := url.Parse(server); err == nil && net.ParseIP(serverURL.Hostname()) != nil {
+			cluster.Spec.PrimaryDomain = serverURL.Hostname() + ".nip.io"
+		} else {
+			problems = append(problems, fmt.Sprintf("the primary domain cannot be derived from the server %s, use --primary-domain", server))
+		}
+	}
+	problems = append(problems, checkCluster(&cluster.Spec)...)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid cluster %s:\n  %s", name, strings.Join(problems, "\n  "))
+	}
+	return cluster, nil
+}
+
+// printManifest prints the resource as a YAML document to stdout, the fields without a value are left out.
+func printManifest(resource interface{}) error {
+	var node yaml.Node
+	if err := node.Encode(resource); err != nil {
+		return fmt.Errorf("unable to marshal resource to yaml: %w", err)
+	}
+	removeEmptyFields(&node)
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return fmt.Errorf("unable to marshal resource to yaml: %w", err)
+	}
+	return encoder.Close()
+}
+
+// removeEmptyFields removes the fields of the mappings of the node whose value is empty, a null, an empty string or an empty collection.
+func removeEmptyFields(node *yaml.Node) {
+	var content []*yaml.Node
+	for i := 0; i < len(node.Content); i++ {
+		child := node.Content[i]
+		removeEmptyFields(child)
+		if node.Kind != yaml.MappingNode {
+			content = append(content, child)
+			continue
+		}
+		value := node.Content[i+1]
+		removeEmptyFields(value)
+		i++
+		if isEmptyNode(value) {
+			continue
+		}
+		content = append(content, child, value)
+	}
+	node.Content = content
+}
+
+func isEmptyNode(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(node.Content) == 0
+	case yaml.ScalarNode:
+		return node.Value == "" || node.Tag == "!!null"
+	default:
+		return false
+	}
+}
@@ -17,6 +17,7 @@ package commands
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/nautes-labs/cli/cmd/printers"
@@ -24,6 +25,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
+	"io"
 	"os"
 	"reflect"
 	"strings"
@@ -35,6 +37,10 @@ const (
 	OutputWide = "wide"
 )
 
+var errProductNotSet = errors.New(`required flag(s) "product" not set, use --product, PRODUCT or a context with a product`)
+
+var errAllProductsWithProduct = errors.New(`flag "all-products" cannot be used with "product"`)
+
 // CheckError logs a fatal message and exits with error code if err is not nil
 func CheckError(err error) {
 	if err != nil {
@@ -61,26 +67,13 @@ func NewResourceCommand(clientOptions *types.ClientOptions, resourceType reflect
 	// Instantiate a ResourceHandler of the specified type
 	resourceHandler := reflect.New(resourceType).Interface().(types.ResourceHandler)
 
-	// Get short commands from tags
-	var shortCommands []string
-	if field, ok := resourceType.FieldByName(types.ResourceKind); ok {
-		tag := field.Tag
-		shortCommands = strings.Split(tag.Get("commands"), ",")
-	}
-
 	// Set the 'kind' value in the ResourceHandler
 	resourcePtr := reflect.ValueOf(resourceHandler).Elem()
 	rType := reflect.TypeOf(resourceHandler).String()
 	resourcePtr.FieldByName(types.ResourceKind).SetString(strings.TrimPrefix(rType, "*types."))
 
-	// Generate resource name and related command names
-	var resourceName = strings.ToLower(resourceHandler.GetKind())
-	var allCmds []string
-	allCmds = append(allCmds, resourceName, fmt.Sprintf("%ss", resourceName))
-	allCmds = append(allCmds, shortCommands...)
-
 	// Create commands using the subCommandFunc for each name
-	for _, cmd := range allCmds {
+	for _, cmd := range resourceCommandNames(resourceType) {
 		command := subCommandFunc(clientOptions, resourceHandler, cmd, resourceType, responseItemType)
 		ccCommands = append(ccCommands, command)
 	}
@@ -89,14 +82,30 @@ func NewResourceCommand(clientOptions *types.ClientOptions, resourceType reflect
 	return ccCommands
 }
 
+// resourceCommandNames returns the names of the commands of a resource type: the resource name, its plural form,
+// and the short commands specified in tags.
+func resourceCommandNames(resourceType reflect.Type) []string {
+	var resourceName = strings.ToLower(resourceType.Name())
+	names := []string{resourceName, fmt.Sprintf("%ss", resourceName)}
+	if field, ok := resourceType.FieldByName(types.ResourceKind); ok {
+		names = append(names, strings.Split(field.Tag.Get("commands"), ",")...)
+	}
+	return names
+}
+
 // SubGetCommand creates a Cobra command for the "get" subcommand of a resource.
 // It retrieves information about a specific resource or a list of resources based on the provided arguments.
 // The command supports various output formats such as json, yaml, or a wide table format.
 // The "product" flag allows filtering resources by product name.
 func SubGetCommand(clientOptions *types.ClientOptions, resourceHandler types.ResourceHandler, resourceName string, _, responseItemType reflect.Type) *cobra.Command {
 	var (
-		output  string
-		product string
+		output        string
+		columns       string
+		product       string
+		allProducts   bool
+		sortBy        string
+		fieldSelector string
+		nameRegex     string
 	)
 
 	// Reflect on the resource handler and initialize some variables
@@ -115,51 +124,60 @@ nautes get %s name-101 name-102`, resourceName, resourceName),
 		Run: func(c *cobra.Command, args []string) {
 			var responseValue reflect.Value
 
+			// Check the output format before the requests
+			printItem, err := newItemPrinter(output)
+			CheckError(err)
+			tableOptions, err := newTableOptions(output, columns)
+			CheckError(err)
+			selector, err := newItemSelector(sortBy, fieldSelector, nameRegex)
+			CheckError(err)
+			// The requests are logged to stderr unless a table of the tags is printed, so that the output can be piped
+			var requestLog io.Writer = os.Stdout
+			if tableOptions == nil || tableOptions.customColumns != nil {
+				requestLog = os.Stderr
+			}
+
 			// Process the "product" flag to filter resources by product name
-			if product != "" {
-				if resourceKind != IgnoreProductOfCluster && resourceKind != IgnoreProductOfProduct {
-					if resourceKind == CodeRepoBinding {
-						resourceValue.FieldByName("Spec").FieldByName("ProductName").SetString(product)
-					} else {
-						resourceValue.FieldByName("Spec").FieldByName("Product").SetString(product)
-					}
+			if allProducts && product != "" {
+				CheckError(errAllProductsWithProduct)
+			}
+			if isProductScoped(resourceKind) && !allProducts {
+				if product == "" {
+					product = clientOptions.Product
+				}
+				if product == "" {
+					CheckError(errProductNotSet)
 				}
+				setResourceProduct(resourceHandler, product)
 			}
 
 			var outputFlag bool
 			resourceResponseList := make([]interface{}, 0)
 			resourceResponseListValue := make([]reflect.Value, 0)
+			// The products of the resources listed in every product
+			var products []string
 
-			if len(args) == 0 {
-				// Retrieve a list of resources
-				// Dynamic creation of a struct for storing resource items
-				sliceType := reflect.SliceOf(responseItemType)
-				sliceValue := reflect.MakeSlice(sliceType, 5, 10)
-				slice := sliceValue.Interface()
-				fields := []reflect.StructField{
-					{
-						Name: "Items",
-						Type: reflect.TypeOf(slice),
-					},
-				}
-				customStructType := reflect.StructOf(fields)
-				responseValue = reflect.New(customStructType)
-
-				// Build and retrieve resources from the server
-				resBytes, err := buildResourceAndDo(MethodGet, clientOptions.ServerAddr, clientOptions.Token, clientOptions.SkipCheck, resourceHandler)
-				if err != nil {
-					CheckError(err)
-				}
-				err = json.Unmarshal(resBytes, responseValue.Interface())
-				if err != nil {
-					CheckError(err)
+			if allProducts {
+				// Retrieve the resources of every product, and keep the ones of the names if any
+				productNames, err := listProductNames(clientOptions)
+				CheckError(err)
+				kind := types.ResourcesType{ResourceType: reflect.TypeOf(resourceHandler).Elem(), ResponseItemType: responseItemType}
+				items, itemProducts, err := listAllProducts(clientOptions, productNames, kind)
+				CheckError(err)
+				products = make([]string, 0, len(items))
+				for i, item := range items {
+					if len(args) > 0 && !containsString(args, reflect.Indirect(item).FieldByName("Name").String()) {
+						continue
+					}
+					resourceResponseList = append(resourceResponseList, item.Interface())
+					resourceResponseListValue = append(resourceResponseListValue, item)
+					products = append(products, itemProducts[i])
 				}
-
-				// Extract individual resource items from the response
-				instance := responseValue.Elem().FieldByName("Items")
-				instanceLen := instance.Len()
-				for i := 0; i < instanceLen; i++ {
-					item := instance.Index(i)
+			} else if len(args) == 0 {
+				// Retrieve a list of resources
+				items, err := listResources(clientOptions, resourceHandler, responseItemType, requestLog)
+				CheckError(err)
+				for _, item := range items {
 					resourceResponseList = append(resourceResponseList, item.Interface())
 					resourceResponseListValue = append(resourceResponseListValue, item)
 				}
@@ -167,7 +185,7 @@ nautes get %s name-101 name-102`, resourceName, resourceName),
 				// Retrieve specific resources by name
 				for _, argsSelector := range args {
 					resourceValue.FieldByName("Spec").FieldByName("Name").SetString(argsSelector)
-					resBytes, err := buildResourceAndDo(MethodGet, clientOptions.ServerAddr, clientOptions.Token, clientOptions.SkipCheck, resourceHandler)
+					resBytes, err := buildResourceAndDo(MethodGet, clientOptions.ServerAddr, clientOptions.Token, clientOptions.SkipCheck, resourceHandler, requestLog)
 					if err != nil {
 						CheckError(err)
 					}
@@ -184,38 +202,49 @@ nautes get %s name-101 name-102`, resourceName, resourceName),
 				}
 			}
 
+			// Sort and filter the resources on the client
+			if selector != nil {
+				resourceResponseListValue, products, err = selector.apply(resourceResponseListValue, products)
+				CheckError(err)
+				resourceResponseList = resourceResponseList[:0]
+				for _, value := range resourceResponseListValue {
+					resourceResponseList = append(resourceResponseList, value.Interface())
+				}
+			}
+
 			// Output formatting based on the specified format
 			switch output {
 			case OutputYaml, OutputJson:
 				err := PrintResourceResponseList(resourceResponseList, output, outputFlag)
 				CheckError(err)
-			case OutputWide, "":
-				table, err := printers.GenerateTable(resourceResponseListValue, responseItemType)
-				CheckError(err)
-				err = printers.PrintTable(table, os.Stdout)
-				CheckError(err)
 			default:
-				CheckError(fmt.Errorf("unknown output format: %s", output))
+				if tableOptions != nil {
+					table, err := tableOptions.generateTable(resourceResponseListValue, products, responseItemType)
+					CheckError(err)
+					err = printers.PrintTable(table, os.Stdout)
+					CheckError(err)
+					return
+				}
+				if printItem == nil {
+					CheckError(fmt.Errorf("unknown output format: %s", output))
+				}
+				for _, item := range resourceResponseList {
+					CheckError(printItem(os.Stdout, resourceKind, item))
+				}
 			}
 		},
 	}
 
 	// Add flags to the command
-	command.Flags().StringVarP(&output, "output", "o", "wide", "Output format. One of: json|yaml|wide")
+	command.Flags().StringVarP(&output, "output", "o", "", outputFlagUsage)
+	command.Flags().StringVar(&columns, "columns", "", columnsFlagUsage)
+	command.Flags().StringVar(&sortBy, "sort-by", "", sortByFlagUsage)
+	command.Flags().StringVar(&fieldSelector, "field-selector", "", fieldSelectorFlagUsage)
+	command.Flags().StringVar(&nameRegex, "name-regex", "", nameRegexFlagUsage)
 	if resourceKind != IgnoreProductOfCluster && resourceKind != IgnoreProductOfProduct {
+		// The product falls back to the PRODUCT environment variable and the product of the context
 		command.Flags().StringVarP(&product, "product", "p", "", "List resource by product name")
-		err := command.MarkFlagRequired("product")
-		if err != nil {
-			CheckError(err)
-		}
-
-		// Set the "product" flag from the environment variable if present
-		if os.Getenv("PRODUCT") != "" {
-			err = command.Flags().Set("product", os.Getenv("PRODUCT"))
-			if err != nil {
-				CheckError(err)
-			}
-		}
+		command.Flags().BoolVarP(&allProducts, "all-products", "A", false, "List resource in every product")
 	}
 
 	return command
@@ -243,14 +272,14 @@ nautes delete %s name-101 name-102`, resourceName, resourceName),
 				c.HelpFunc()(c, args)
 				os.Exit(1)
 			}
-			if product != "" {
-				if resourceKind != IgnoreProductOfCluster && resourceKind != IgnoreProductOfProduct {
-					if resourceKind == CodeRepoBinding {
-						resourceValue.FieldByName("Spec").FieldByName("ProductName").SetString(product)
-					} else {
-						resourceValue.FieldByName("Spec").FieldByName("Product").SetString(product)
-					}
+			if isProductScoped(resourceKind) {
+				if product == "" {
+					product = clientOptions.Product
+				}
+				if product == "" {
+					CheckError(errProductNotSet)
 				}
+				setResourceProduct(resourceHandler, product)
 			}
 			var isConfirmAll bool
 			for _, argsSelector := range args {
@@ -272,7 +301,7 @@ nautes delete %s name-101 name-102`, resourceName, resourceName),
 
 				if lowercaseAnswer == "y" {
 					resourceValue.FieldByName("Spec").FieldByName("Name").SetString(argsSelector)
-					_, err := buildResourceAndDo(MethodDelete, clientOptions.ServerAddr, clientOptions.Token, clientOptions.SkipCheck, resourceHandler)
+					_, err := buildResourceAndDo(MethodDelete, clientOptions.ServerAddr, clientOptions.Token, clientOptions.SkipCheck, resourceHandler, os.Stdout)
 					if err != nil {
 						CheckError(err)
 					}
@@ -286,21 +315,43 @@ nautes delete %s name-101 name-102`, resourceName, resourceName),
 	command.Flags().BoolVarP(&noPrompt, "yes", "y", false, "Turn off prompting to confirm remove of resources")
 	if resourceKind != IgnoreProductOfCluster && resourceKind != IgnoreProductOfProduct {
 		command.Flags().StringVarP(&product, "product", "p", "", "List resource by product name")
-		err := command.MarkFlagRequired("product")
-		if err != nil {
-			CheckError(err)
-		}
-
-		if os.Getenv("PRODUCT") != "" {
-			err = command.Flags().Set("product", os.Getenv("PRODUCT"))
-			if err != nil {
-				CheckError(err)
-			}
-		}
 	}
 	return command
 }
 
+// listResources retrieves the list of resources of the kind of the resource handler,
+// the product of the resource handler must be set for product-scoped kinds.
+func listResources(clientOptions *types.ClientOptions, resourceHandler types.ResourceHandler, responseItemType reflect.Type, out io.Writer) ([]reflect.Value, error) {
+	// Dynamic creation of a struct for storing resource items
+	sliceType := reflect.SliceOf(responseItemType)
+	fields := []reflect.StructField{
+		{
+			Name: "Items",
+			Type: sliceType,
+		},
+	}
+	customStructType := reflect.StructOf(fields)
+	responseValue := reflect.New(customStructType)
+
+	// Build and retrieve resources from the server
+	resBytes, err := buildResourceAndDo(MethodGet, clientOptions.ServerAddr, clientOptions.Token, clientOptions.SkipCheck, resourceHandler, out)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(resBytes, responseValue.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract individual resource items from the response
+	var items []reflect.Value
+	instance := responseValue.Elem().FieldByName("Items")
+	for i := 0; i < instance.Len(); i++ {
+		items = append(items, instance.Index(i))
+	}
+	return items, nil
+}
+
 // AskToProceedS prompts the user with a message (typically a yes, no or all question) and returns string
 // "a", "y" or "n".
 func AskToProceedS(message string) string {
@@ -0,0 +1,281 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/config"
+	"github.com/nautes-labs/cli/cmd/printers"
+	"github.com/nautes-labs/cli/cmd/types"
+	"github.com/spf13/cobra"
+	"os"
+	"reflect"
+)
+
+const (
+	FlagAPIServer = "api-server"
+	FlagToken     = "token"
+	FlagInsecure  = "insecure"
+
+	EnvAPIServer = "API_SERVER"
+	EnvToken     = "GIT_TOKEN"
+	EnvProduct   = "PRODUCT"
+
+	redactedToken = "REDACTED"
+)
+
+// contextRow is a row of the get-contexts table.
+type contextRow struct {
+	Current string `column:"current"`
+	Name    string `column:"name"`
+	Server  string `column:"server"`
+	Product string `column:"product"`
+}
+
+// ResolveClientOptions fills the client options that were not set by flags.
+// The order of precedence is: command line flags, environment variables, then the selected context of the config file.
+func ResolveClientOptions(c *cobra.Command, clientOptions *types.ClientOptions) error {
+	cfg, _, err := loadConfig(clientOptions)
+	if err != nil {
+		return err
+	}
+
+	contextName := clientOptions.Context
+	if contextName == "" {
+		contextName = os.Getenv(config.EnvContext)
+	}
+	if contextName == "" {
+		contextName = cfg.CurrentContext
+	}
+	var context *config.Context
+	if contextName != "" {
+		context = cfg.GetContext(contextName)
+		if context == nil {
+			return fmt.Errorf("context %s not found in config file", contextName)
+		}
+	} else {
+		context = &config.Context{}
+	}
+
+	flags := c.Flags()
+	if !flags.Changed(FlagAPIServer) {
+		clientOptions.ServerAddr = firstNonEmpty(os.Getenv(EnvAPIServer), context.Server)
+	}
+	// The default of the context only applies to the commands that have the flag.
+	if flags.Lookup(FlagInsecure) != nil && !flags.Changed(FlagInsecure) {
+		clientOptions.SkipCheck = context.InsecureSkipCheck
+	}
+	clientOptions.Product = firstNonEmpty(os.Getenv(EnvProduct), context.Product)
+
+	if !requiresAPIServer(c) {
+		return nil
+	}
+	// The token of the context may be read from an environment variable or a command, it is only resolved for the API server.
+	if !flags.Changed(FlagToken) {
+		clientOptions.Token = os.Getenv(EnvToken)
+		if clientOptions.Token == "" {
+			clientOptions.Token, err = context.ResolveToken()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if clientOptions.ServerAddr == "" {
+		return fmt.Errorf("api server is not set, use --%s, %s or a context in the config file", FlagAPIServer, EnvAPIServer)
+	}
+	if clientOptions.Token == "" {
+		return fmt.Errorf("token is not set, use --%s, %s or a context in the config file", FlagToken, EnvToken)
+	}
+	return nil
+}
+
+// NewConfigCommand creates the "config" command and its subcommands which manage the contexts of the config file.
+func NewConfigCommand(clientOptions *types.ClientOptions) *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the contexts of the config file",
+		Long: `Manage the contexts of the config file, ~/.nautes/config by default.
+
+A context holds the API server, the token source, the default product and the default of the insecure flag.
+Command line flags take precedence over the environment variables API_SERVER, GIT_TOKEN and PRODUCT,
+which take precedence over the selected context.`,
+		// The config commands do not talk to the API server, so the client options are not resolved.
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			return nil
+		},
+		Run: func(c *cobra.Command, args []string) {
+			c.HelpFunc()(c, args)
+		},
+	}
+	command.AddCommand(newUseContextCommand(clientOptions))
+	command.AddCommand(newGetContextsCommand(clientOptions))
+	command.AddCommand(newSetContextCommand(clientOptions))
+	command.AddCommand(newViewCommand(clientOptions))
+	return command
+}
+
+func newUseContextCommand(clientOptions *types.ClientOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:     "use-context name",
+		Short:   "Set the current context",
+		Example: "nautes config use-context dev",
+		Args:    cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			cfg, path, err := loadConfig(clientOptions)
+			CheckError(err)
+			if cfg.GetContext(args[0]) == nil {
+				CheckError(fmt.Errorf("context %s not found in config file", args[0]))
+			}
+			cfg.CurrentContext = args[0]
+			CheckError(cfg.Save(path))
+			fmt.Printf("Switched to context '%s'.\n", args[0])
+		},
+	}
+}
+
+func newGetContextsCommand(clientOptions *types.ClientOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get-contexts",
+		Short: "List the contexts of the config file",
+		Run: func(c *cobra.Command, args []string) {
+			cfg, _, err := loadConfig(clientOptions)
+			CheckError(err)
+			var rows []reflect.Value
+			for _, context := range cfg.Contexts {
+				row := contextRow{
+					Name:    context.Name,
+					Server:  context.Server,
+					Product: context.Product,
+				}
+				if context.Name == cfg.CurrentContext {
+					row.Current = "*"
+				}
+				rows = append(rows, reflect.ValueOf(row))
+			}
+			table, err := printers.GenerateTable(rows, reflect.TypeOf(contextRow{}))
+			CheckError(err)
+			CheckError(printers.PrintTable(table, os.Stdout))
+		},
+	}
+}
+
+func newSetContextCommand(clientOptions *types.ClientOptions) *cobra.Command {
+	var context config.Context
+	var command = &cobra.Command{
+		Use:   "set-context name",
+		Short: "Create a context or update the given fields of an existing context",
+		Example: `nautes config set-context dev --server https://nautes-dev.example.com:8000 --token-env DEV_GIT_TOKEN --product demo
+
+nautes config set-context prod --token-file ~/.nautes/prod-token`,
+		Args: cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			cfg, path, err := loadConfig(clientOptions)
+			CheckError(err)
+			current := cfg.GetContext(args[0])
+			if current == nil {
+				current = &config.Context{Name: args[0]}
+			}
+			flags := c.Flags()
+			if flags.Changed("server") {
+				current.Server = context.Server
+			}
+			if flags.Changed("token") || flags.Changed("token-env") || flags.Changed("token-file") {
+				current.Token = context.Token
+				current.TokenEnv = context.TokenEnv
+				current.TokenFile = context.TokenFile
+			}
+			if flags.Changed("product") {
+				current.Product = context.Product
+			}
+			if flags.Changed("insecure-skip-check") {
+				current.InsecureSkipCheck = context.InsecureSkipCheck
+			}
+			cfg.SetContext(current)
+			if cfg.CurrentContext == "" {
+				cfg.CurrentContext = current.Name
+			}
+			CheckError(cfg.Save(path))
+			fmt.Printf("Context '%s' saved.\n", current.Name)
+		},
+	}
+	command.Flags().StringVar(&context.Server, "server", "", "URL to API server")
+	command.Flags().StringVar(&context.Token, "token", "", "Authentication token, stored as plain text")
+	command.Flags().StringVar(&context.TokenEnv, "token-env", "", "Name of the environment variable holding the token")
+	command.Flags().StringVar(&context.TokenFile, "token-file", "", "Path of the file holding the token")
+	command.Flags().StringVar(&context.Product, "product", "", "Default product name")
+	command.Flags().BoolVar(&context.InsecureSkipCheck, "insecure-skip-check", false, "Skip the compliance check by default")
+	command.MarkFlagsMutuallyExclusive("token", "token-env", "token-file")
+	return command
+}
+
+func newViewCommand(clientOptions *types.ClientOptions) *cobra.Command {
+	var raw bool
+	var command = &cobra.Command{
+		Use:   "view",
+		Short: "Print the config file",
+		Run: func(c *cobra.Command, args []string) {
+			cfg, _, err := loadConfig(clientOptions)
+			CheckError(err)
+			if !raw {
+				for _, context := range cfg.Contexts {
+					if context.Token != "" {
+						context.Token = redactedToken
+					}
+				}
+			}
+			CheckError(PrintResource(cfg, OutputYaml))
+		},
+	}
+	command.Flags().BoolVar(&raw, "raw", false, "Print the tokens instead of redacting them")
+	return command
+}
+
+// loadConfig loads the config file given by the --config flag or the default path, and returns it with its path.
+func loadConfig(clientOptions *types.ClientOptions) (*config.Config, string, error) {
+	path := clientOptions.ConfigPath
+	if path == "" {
+		var err error
+		path, err = config.DefaultPath()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return cfg, path, nil
+}
+
+// requiresAPIServer reports whether the command talks to the API server,
+// commands that only print help or shell completions do not, nor do commands with subcommands given no arguments.
+func requiresAPIServer(c *cobra.Command) bool {
+	if c.HasSubCommands() && c.Flags().NArg() == 0 || c.Name() == "help" {
+		return false
+	}
+	if c.HasParent() && c.Parent().Name() == "completion" {
+		return false
+	}
+	return true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
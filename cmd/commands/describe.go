@@ -0,0 +1,226 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/printers"
+	"github.com/nautes-labs/cli/cmd/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// relatedResource is a resource referring to the described resource, with the path of the field holding the reference.
+type relatedResource struct {
+	Ref  resourceRef
+	Path string
+}
+
+// SubDescribeCommand returns the function creating the "describe" subcommand of a resource,
+// the resources referring to the described resource are searched among the resources of the given types.
+func SubDescribeCommand(resourceTypeArr []types.ResourcesType) func(clientOptions *types.ClientOptions, resourceHandler types.ResourceHandler,
+	resourceName string, resourceType, responseItemType reflect.Type) *cobra.Command {
+	return func(clientOptions *types.ClientOptions, resourceHandler types.ResourceHandler, resourceName string, resourceType, _ reflect.Type) *cobra.Command {
+		var product string
+		resourceKind := resourceHandler.GetKind()
+		var command = &cobra.Command{
+			Use:     fmt.Sprintf("%s name", resourceName),
+			Short:   fmt.Sprintf("Show the details of a %s and the resources related to it", strings.ToUpper(resourceKind)),
+			Example: fmt.Sprintf(`nautes describe %s example-name -p demo`, resourceName),
+			Args:    cobra.ExactArgs(1),
+			Run: func(c *cobra.Command, args []string) {
+				product = firstNonEmpty(product, clientOptions.Product)
+				if isProductScoped(resourceKind) && product == "" {
+					CheckError(errProductNotSet)
+				}
+				CheckError(describeResourceDetails(os.Stdout, clientOptions, resourceTypeArr, newResourceHandler(resourceType, product, args[0]), product))
+			},
+		}
+		usage := "Product of the resource, defaults to $PRODUCT or the product of the context"
+		if !isProductScoped(resourceKind) {
+			usage = "Product whose resources are searched for references to the resource, defaults to $PRODUCT or the product of the context"
+		}
+		command.Flags().StringVarP(&product, "product", "p", "", usage)
+		return command
+	}
+}
+
+// describeResourceDetails gets the resource and prints its spec, the resources it refers to and the resources referring to it.
+// The product is the product searched for the resources referring to the resource.
+func describeResourceDetails(out io.Writer, clientOptions *types.ClientOptions, resourceTypeArr []types.ResourcesType,
+	resourceHandler types.ResourceHandler, product string) error {
+	name := getSpecField(resourceHandler, "Name")
+	resBytes, err := buildResourceAndDo(MethodGet, formatAPIServer(clientOptions.ServerAddr), clientOptions.Token, clientOptions.SkipCheck, resourceHandler, io.Discard)
+	if err != nil {
+		return err
+	}
+	specValue := reflect.ValueOf(resourceHandler).Elem().FieldByName("Spec")
+	if err = json.Unmarshal(resBytes, specValue.Addr().Interface()); err != nil {
+		return err
+	}
+	// The response may leave out the path parameters.
+	specValue.FieldByName("Name").SetString(name)
+	if isProductScoped(resourceHandler.GetKind()) {
+		setResourceProduct(resourceHandler, product)
+	}
+
+	var spec yaml.Node
+	if err = spec.Encode(specValue.Interface()); err != nil {
+		return fmt.Errorf("unable to marshal resource to yaml: %w", err)
+	}
+	removeEmptyFields(&spec)
+	var specBuffer bytes.Buffer
+	encoder := yaml.NewEncoder(&specBuffer)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&spec); err != nil {
+		return fmt.Errorf("unable to marshal resource to yaml: %w", err)
+	}
+
+	w := printers.GetNewTabWriter(out)
+	fmt.Fprintf(w, "Kind:\t%s\n", resourceHandler.GetKind())
+	fmt.Fprintf(w, "Name:\t%s\n", name)
+	if isProductScoped(resourceHandler.GetKind()) {
+		fmt.Fprintf(w, "Product:\t%s\n", product)
+	}
+	fmt.Fprintln(w, "Spec:")
+	for _, line := range strings.Split(strings.TrimRight(specBuffer.String(), "\n"), "\n") {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+
+	fmt.Fprintln(w, "Refers to:")
+	resolver := newRemoteResolver(clientOptions, resourceTypeArr)
+	refs := resourceRefs(resourceHandler)
+	for _, ref := range refs {
+		found, err := resolver.exists(ref.Ref)
+		if err != nil {
+			return err
+		}
+		status := ""
+		if !found {
+			status = "(not found)"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", refTitle(ref.Ref, product), ref.Path, status)
+	}
+	if len(refs) == 0 {
+		fmt.Fprintln(w, "  <none>")
+	}
+
+	fmt.Fprintln(w, "Referred to by:")
+	related, err := findRelatedResources(clientOptions, resourceTypeArr, resourceHandler, product)
+	if err != nil {
+		return err
+	}
+	for _, resource := range related {
+		fmt.Fprintf(w, "  %s\t%s\t\n", refTitle(resource.Ref, product), resource.Path)
+	}
+	if len(related) == 0 {
+		fmt.Fprintln(w, "  <none>")
+	}
+	return w.Flush()
+}
+
+// refTitle returns the title of the resource, without its product when it is the given product.
+func refTitle(ref resourceRef, product string) string {
+	if ref.Product == product {
+		ref.Product = ""
+	}
+	return ref.String()
+}
+
+// findRelatedResources lists the resources of the kinds that can refer to the kind of the resource, and returns the ones referring to it.
+// Product-scoped kinds are listed in the product, and are not searched without a product.
+// The kinds that can refer to the resources of another product, such as code repo bindings granting projects, are listed in every product.
+// The resources of a product are not searched for references to the product.
+func findRelatedResources(clientOptions *types.ClientOptions, resourceTypeArr []types.ResourcesType,
+	resourceHandler types.ResourceHandler, product string) ([]relatedResource, error) {
+	kind := resourceHandler.GetKind()
+	if kind == IgnoreProductOfProduct {
+		return nil, nil
+	}
+	target := resourceRef{Kind: kind, Product: getResourceProduct(resourceHandler), Name: getSpecField(resourceHandler, "Name")}
+
+	var related []relatedResource
+	var productNames []string
+	for _, value := range resourceTypeArr {
+		scoped := isProductScoped(value.ResourceType.Name())
+		refers, otherProducts := refersToKind(value.ResponseItemType, kind)
+		if scoped && product == "" || !refers {
+			continue
+		}
+		var items []reflect.Value
+		var products []string
+		var err error
+		if scoped && otherProducts {
+			if productNames == nil {
+				if productNames, err = listProductNames(clientOptions); err != nil {
+					return nil, err
+				}
+			}
+			items, products, err = listAllProducts(clientOptions, productNames, value)
+		} else {
+			items, err = listResources(clientOptions, newResourceHandler(value.ResourceType, product, ""), value.ResponseItemType, io.Discard)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			handler := newResourceHandler(value.ResourceType, "", "")
+			reflect.ValueOf(handler).Elem().FieldByName("Spec").Set(item)
+			if products != nil {
+				setResourceProduct(handler, products[i])
+			} else if scoped {
+				setResourceProduct(handler, product)
+			}
+			source := resourceRef{Kind: value.ResourceType.Name(), Product: getResourceProduct(handler), Name: getSpecField(handler, "Name")}
+			for _, ref := range resourceRefs(handler) {
+				if ref.Ref == target {
+					related = append(related, relatedResource{Ref: source, Path: ref.Path})
+				}
+			}
+		}
+	}
+	return related, nil
+}
+
+// refersToKind reports whether a field of the type, or of the types it holds, is tagged with a reference to the kind,
+// and whether such a field is tagged with the field holding the product of its references.
+func refersToKind(t reflect.Type, kind string) (refers bool, otherProducts bool) {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		return refersToKind(t.Elem(), kind)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if ref, ok := field.Tag.Lookup(types.Ref); ok && ref == kind {
+				refers = true
+				if _, ok := field.Tag.Lookup(types.RefProduct); ok {
+					otherProducts = true
+				}
+				continue
+			}
+			if fieldRefers, fieldOtherProducts := refersToKind(field.Type, kind); fieldRefers {
+				refers = true
+				otherProducts = otherProducts || fieldOtherProducts
+			}
+		}
+	}
+	return refers, otherProducts
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/types"
+	"gopkg.in/yaml.v3"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DiffCreated   = "created"
+	DiffChanged   = "changed"
+	DiffUnchanged = "unchanged"
+)
+
+// fieldChange is the difference of a single field between the live spec and the spec of the manifest.
+// An empty live or desired value means that the field is not set on that side.
+type fieldChange struct {
+	Path    string
+	Live    string
+	Desired string
+}
+
+// DiffOptions holds the options of a diff run.
+type DiffOptions struct {
+	// FileOptions are the files declaring the resources.
+	FileOptions
+	// Out receives the diff.
+	Out io.Writer
+}
+
+// Diff compares every resource of the file with the live resource on the API server and prints a field-level diff of the spec.
+// It returns true if applying the file would create or change at least one resource.
+func Diff(clientOptions *types.ClientOptions, diffOptions *DiffOptions, resourceTypeArr []types.ResourcesType) (bool, error) {
+	apiServer := formatAPIServer(clientOptions.ServerAddr)
+	token, skipCheck, out := clientOptions.Token, clientOptions.SkipCheck, diffOptions.Out
+
+	resourcesMap, err := loadResourcesMap(&diffOptions.FileOptions, resourceTypeArr, out)
+	if err != nil {
+		return false, fmt.Errorf("failed to load resource file: %w", err)
+	}
+
+	var created, changed, unchanged int
+	for _, value := range resourceTypeArr {
+		typeName := value.ResourceType.Name()
+		for _, document := range resourcesMap[typeName] {
+			resourceObj := reflect.New(value.ResourceType).Interface().(types.ResourceHandler)
+			if err = decodeResource(document.Node, resourceObj); err != nil {
+				return false, fmt.Errorf("%s: %w", document.position(), err)
+			}
+			if cluster, ok := resourceObj.(*types.Cluster); ok && cluster.Spec.KubeconfigFrom != nil {
+				// The kubeconfigFrom is not loaded nor sent, it is a declared kubeconfig which is always a change.
+				cluster.Spec.Kubeconfig, cluster.Spec.KubeconfigFrom = unloadedKubeconfig, nil
+			}
+			status, changes, err := diffResource(apiServer, token, skipCheck, resourceObj)
+			if err != nil {
+				return false, err
+			}
+			printResourceDiff(out, resourceObj, status, changes)
+			switch status {
+			case DiffCreated:
+				created++
+			case DiffChanged:
+				changed++
+			default:
+				unchanged++
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "\n%d to create, %d to change, %d unchanged.\n", created, changed, unchanged)
+	return created+changed > 0, nil
+}
+
+// diffResource gets the live resource from the API server and compares it with the spec of the resource handler.
+func diffResource(apiServer string, token string, skipCheck bool, resourceHandler types.ResourceHandler) (string, []fieldChange, error) {
+	liveSpec, err := getLiveSpec(apiServer, token, skipCheck, resourceHandler)
+	if err != nil {
+		if IsNotFound(err) {
+			changes, err := compareSpec(resourceHandler.GetKind(), nil, getSpec(resourceHandler))
+			return DiffCreated, changes, err
+		}
+		return "", nil, err
+	}
+
+	changes, err := compareSpec(resourceHandler.GetKind(), liveSpec, getSpec(resourceHandler))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(changes) == 0 {
+		return DiffUnchanged, nil, nil
+	}
+	return DiffChanged, changes, nil
+}
+
+// isResourceUnchanged reports whether the live resource on the API server already matches the resource.
+func isResourceUnchanged(apiServer string, clientOptions *types.ClientOptions, resourceHandler types.ResourceHandler) (bool, error) {
+	status, _, err := diffResource(apiServer, clientOptions.Token, clientOptions.SkipCheck, resourceHandler)
+	if err != nil {
+		return false, err
+	}
+	return status == DiffUnchanged, nil
+}
+
+// getLiveSpec gets the resource from the API server by the path of the resource handler and decodes it into a new spec.
+func getLiveSpec(apiServer string, token string, skipCheck bool, resourceHandler types.ResourceHandler) (interface{}, error) {
+	resBytes, err := buildResourceAndDo(MethodGet, apiServer, token, skipCheck, resourceHandler, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	liveSpec := reflect.New(reflect.ValueOf(getSpec(resourceHandler)).Type())
+	if err = json.Unmarshal(resBytes, liveSpec.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", resourceHandler.GetKind(), err)
+	}
+	return liveSpec.Elem().Interface(), nil
+}
+
+// compareSpec compares two specs of the given kind field by field using the YAML field names of the manifest.
+// Fields holding a zero value are treated as not set, and the defaults of the API server are filled in before comparing.
+func compareSpec(kind string, live, desired interface{}) ([]fieldChange, error) {
+	liveFields, err := flattenSpec(live)
+	if err != nil {
+		return nil, err
+	}
+	desiredFields, err := flattenSpec(desired)
+	if err != nil {
+		return nil, err
+	}
+	normalizeFields(kind, liveFields, desiredFields)
+
+	paths := make([]string, 0, len(liveFields)+len(desiredFields))
+	for path := range liveFields {
+		paths = append(paths, path)
+	}
+	for path := range desiredFields {
+		if _, ok := liveFields[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var changes []fieldChange
+	for _, path := range paths {
+		if liveFields[path] != desiredFields[path] {
+			changes = append(changes, fieldChange{Path: path, Live: liveFields[path], Desired: desiredFields[path]})
+		}
+	}
+	return changes, nil
+}
+
+// flattenSpec converts a spec into a map of field paths, such as "git.gitlab.path" or "pipelines[0].name", to scalar values.
+func flattenSpec(spec interface{}) (map[string]string, error) {
+	fields := make(map[string]string)
+	if spec == nil {
+		return fields, nil
+	}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal spec to yaml: %w", err)
+	}
+	var generic interface{}
+	if err = yaml.Unmarshal(yamlBytes, &generic); err != nil {
+		return nil, fmt.Errorf("error unmarshaling YAML: %w", err)
+	}
+	flattenValue("", generic, fields)
+	return fields, nil
+}
+
+func flattenValue(path string, value interface{}, fields map[string]string) {
+	switch val := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		for key, item := range val {
+			if path == "" {
+				flattenValue(key, item, fields)
+			} else {
+				flattenValue(path+"."+key, item, fields)
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			flattenValue(fmt.Sprintf("%s[%d]", path, i), item, fields)
+		}
+	case string:
+		if val == "" {
+			return
+		}
+		if strings.Contains(val, "\n") {
+			val = strconv.Quote(val)
+		}
+		fields[path] = val
+	case bool:
+		if val {
+			fields[path] = "true"
+		}
+	default:
+		str := fmt.Sprint(val)
+		if str != "0" {
+			fields[path] = str
+		}
+	}
+}
+
+func printResourceDiff(out io.Writer, resourceHandler types.ResourceHandler, status string, changes []fieldChange) {
+	title := describeResource(resourceHandler)
+	if status == DiffUnchanged {
+		fmt.Fprintf(out, "%s unchanged\n", title)
+		return
+	}
+
+	fmt.Fprintf(out, "%s %s\n", title, status)
+	name := fmt.Sprintf("%s/%s", resourceHandler.GetKind(), getSpecField(resourceHandler, "Name"))
+	if status == DiffCreated {
+		fmt.Fprintf(out, "--- /dev/null\n")
+	} else {
+		fmt.Fprintf(out, "--- live/%s\n", name)
+	}
+	fmt.Fprintf(out, "+++ manifest/%s\n", name)
+	for _, change := range changes {
+		if change.Live != "" {
+			fmt.Fprintf(out, "-  %s: %s\n", change.Path, change.Live)
+		}
+		if change.Desired != "" {
+			fmt.Fprintf(out, "+  %s: %s\n", change.Path, change.Desired)
+		}
+	}
+	fmt.Fprintln(out)
+}
+
+// describeResource returns the kind, name and product of a resource, such as "CodeRepo 'repo-a' of product 'demo'".
+func describeResource(resourceHandler types.ResourceHandler) string {
+	title := fmt.Sprintf("%s '%s'", resourceHandler.GetKind(), getSpecField(resourceHandler, "Name"))
+	if product := getResourceProduct(resourceHandler); product != "" {
+		title = fmt.Sprintf("%s of product '%s'", title, product)
+	}
+	return title
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FlagFile      = "file"
+	FlagRecursive = "recursive"
+)
+
+const (
+	// Stdin is the file name reading the resources from the standard input.
+	Stdin = "-"
+	// stdinName is the name of the standard input in error messages.
+	stdinName = "<stdin>"
+)
+
+// resourceFileExtensions are the extensions of the files read from a directory.
+var resourceFileExtensions = []string{".yaml", ".yml", ".json"}
+
+// FileOptions holds the files the resources are read from.
+type FileOptions struct {
+	// Filenames are files, directories, glob patterns or Stdin. The documents of all of them are merged into one run.
+	Filenames []string
+	// Overlays are files, directories or glob patterns of overlay documents, which are merged into the documents of Filenames.
+	Overlays []string
+	// Recursive reads the files of the subdirectories of the given directories.
+	Recursive bool
+	// Strict rejects the fields of the files that are not known to the kind of their resource.
+	Strict bool
+	// Vars are the values of the placeholders of the files as name=value, they take precedence over the variable files.
+	Vars []string
+	// VarFiles are YAML files mapping the names of the placeholders to their values.
+	VarFiles []string
+	// ExpandEnv takes the value of a placeholder without a variable from the environment variable of the same name.
+	ExpandEnv bool
+	// ResolveKubeconfig loads the kubeconfig of the clusters declaring a kubeconfigFrom, it is only set by apply which sends the clusters,
+	// since loading may run the command of the source.
+	ResolveKubeconfig bool
+}
+
+// AddFileFlags adds the flags selecting the files the resources are read from to the command, the file flag is required.
+func AddFileFlags(c *cobra.Command, fileOptions *FileOptions) {
+	addFileFlags(c, fileOptions)
+	c.Flags().Lookup(FlagFile).Usage += " (required)"
+	CheckError(c.MarkFlagRequired(FlagFile))
+}
+
+// addFileFlags adds the flags selecting the files the resources are read from to the command.
+func addFileFlags(c *cobra.Command, fileOptions *FileOptions) {
+	c.Flags().StringSliceVarP(&fileOptions.Filenames, FlagFile, "f", nil,
+		"Files, directories or glob patterns declaring the resources, - reads the standard input. Can be repeated")
+	c.Flags().StringSliceVar(&fileOptions.Overlays, FlagOverlay, nil,
+		"Files, directories or glob patterns of overlays merged into the resources of the same kind and spec.name. Can be repeated")
+	c.Flags().BoolVarP(&fileOptions.Recursive, FlagRecursive, "R", false, "Read the files of the subdirectories of the given directories")
+	c.Flags().BoolVar(&fileOptions.Strict, FlagStrict, true, "Reject the fields of the files that are not known to the kind of their resource")
+	c.Flags().StringArrayVar(&fileOptions.Vars, FlagVar, nil, "Value of a placeholder of the files as name=value, such as suffix=101. Can be repeated")
+	c.Flags().StringArrayVar(&fileOptions.VarFiles, FlagVarFile, nil, "YAML file mapping the names of the placeholders to their values. Can be repeated")
+	c.Flags().BoolVar(&fileOptions.ExpandEnv, FlagExpandEnv, false, "Take the value of a placeholder without a variable from the environment")
+}
+
+// readsStdin reports whether the resources are read from the standard input.
+func (o *FileOptions) readsStdin() bool {
+	for _, filename := range append(o.Filenames, o.Overlays...) {
+		if filename == Stdin {
+			return true
+		}
+	}
+	return false
+}
+
+// expandFilenames expands the directories and glob patterns into files, keeping the order of the given file names.
+// The files of a directory are the ones with a resource file extension, in lexical order.
+func expandFilenames(filenames []string, recursive bool) ([]string, error) {
+	var files []string
+	seen := make(map[string]struct{})
+	add := func(file string) {
+		if _, ok := seen[file]; !ok {
+			seen[file] = struct{}{}
+			files = append(files, file)
+		}
+	}
+
+	for _, filename := range filenames {
+		if filename == Stdin {
+			add(filename)
+			continue
+		}
+
+		paths := []string{filename}
+		if strings.ContainsAny(filename, "*?[") {
+			matches, err := filepath.Glob(filename)
+			if err != nil {
+				return nil, fmt.Errorf("invalid file pattern %s: %w", filename, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match the pattern %s", filename)
+			}
+			paths = matches
+		}
+
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading file: %w", err)
+			}
+			if !info.IsDir() {
+				add(path)
+				continue
+			}
+			dirFiles, err := listResourceFiles(path, recursive)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range dirFiles {
+				add(file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// listResourceFiles lists the files of the directory with a resource file extension.
+func listResourceFiles(dir string, recursive bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isResourceFile(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+func isResourceFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, resourceExt := range resourceFileExtensions {
+		if ext == resourceExt {
+			return true
+		}
+	}
+	return false
+}
+
+// readResourceFile reads the content of the file, or of the standard input, and returns the name used in error messages.
+func readResourceFile(filename string) (string, []byte, error) {
+	if filename == Stdin {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", nil, fmt.Errorf("error reading stdin: %w", err)
+		}
+		return stdinName, content, nil
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return filename, content, nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandFilenames(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"b.yaml", "a.yml", "c.json", "readme.md", "sub/d.yaml"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	in := func(files ...string) []string {
+		paths := make([]string, 0, len(files))
+		for _, file := range files {
+			if file == Stdin {
+				paths = append(paths, file)
+				continue
+			}
+			paths = append(paths, filepath.Join(dir, file))
+		}
+		return paths
+	}
+
+	tests := []struct {
+		name      string
+		filenames []string
+		recursive bool
+		want      []string
+		wantErr   bool
+	}{
+		{name: "files in the given order", filenames: in("c.json", "b.yaml"), want: in("c.json", "b.yaml")},
+		{name: "directory", filenames: in("."), want: in("a.yml", "b.yaml", "c.json")},
+		{name: "recursive directory", filenames: in("."), recursive: true, want: in("a.yml", "b.yaml", "c.json", "sub/d.yaml")},
+		{name: "glob", filenames: in("*.y*ml"), want: in("a.yml", "b.yaml")},
+		{name: "duplicates read once", filenames: in("b.yaml", ".", "b.yaml"), want: in("b.yaml", "a.yml", "c.json")},
+		{name: "stdin", filenames: in(Stdin, "a.yml"), want: in(Stdin, "a.yml")},
+		{name: "glob without match", filenames: in("*.txt"), wantErr: true},
+		{name: "missing file", filenames: in("missing.yaml"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandFilenames(tt.filenames, tt.recursive)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
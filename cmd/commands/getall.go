@@ -0,0 +1,326 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/printers"
+	"github.com/nautes-labs/cli/cmd/types"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// KindsAll selects every product-scoped kind in a get of several kinds.
+const KindsAll = "all"
+
+// listParallelism is the number of list requests the get commands send at the same time.
+const listParallelism = 8
+
+// GetOptions holds the options of a get of several kinds.
+type GetOptions struct {
+	// Kinds are the command names of the kinds separated by commas, such as env,cr,dr, or KindsAll.
+	Kinds string
+	// Product is the product of the product-scoped kinds.
+	Product string
+	// AllProducts lists the product-scoped kinds in every product instead of the product.
+	AllProducts bool
+	// Output is the output format. One of: json|yaml|wide|name|custom-columns=...|jsonpath=...|go-template=...|go-template-file=..., or empty for a table.
+	Output string
+	// Columns are the columns added to or removed from the tables, see newTableOptions.
+	Columns string
+	// SortBy, FieldSelector and NameRegex sort and filter the resources of every kind, see newItemSelector.
+	SortBy        string
+	FieldSelector string
+	NameRegex     string
+	// Out receives the resources.
+	Out io.Writer
+}
+
+// kindItems are the resources listed for a kind.
+type kindItems struct {
+	resourceType types.ResourcesType
+	items        []reflect.Value
+	// products are the products of the items when they are listed in every product.
+	products []string
+	err      error
+}
+
+// kindItem is a resource of a get of several kinds, it carries its kind like a resource of a file.
+type kindItem struct {
+	APIVersion string      `yaml:"apiVersion" json:"api_version"`
+	Kind       string      `yaml:"kind" json:"kind"`
+	Spec       interface{} `yaml:"spec" json:"spec"`
+}
+
+// GetResources lists the resources of several kinds concurrently, and prints a table for every kind with resources,
+// or with json and yaml a single list of the resources of every kind.
+func GetResources(clientOptions *types.ClientOptions, getOptions *GetOptions, resourceTypeArr []types.ResourcesType) error {
+	selected, err := resolveKinds(getOptions.Kinds, resourceTypeArr)
+	if err != nil {
+		return err
+	}
+	printItem, err := newItemPrinter(getOptions.Output)
+	if err != nil {
+		return err
+	}
+	tableOptions, err := newTableOptions(getOptions.Output, getOptions.Columns)
+	if err != nil {
+		return err
+	}
+	selector, err := newItemSelector(getOptions.SortBy, getOptions.FieldSelector, getOptions.NameRegex)
+	if err != nil {
+		return err
+	}
+	if getOptions.AllProducts && getOptions.Product != "" {
+		return errAllProductsWithProduct
+	}
+	for _, value := range selected {
+		if isProductScoped(value.ResourceType.Name()) && getOptions.Product == "" && !getOptions.AllProducts {
+			return errProductNotSet
+		}
+	}
+
+	// The products are listed once for all the kinds listed in every product.
+	var productNames []string
+	for _, value := range selected {
+		if getOptions.AllProducts && isProductScoped(value.ResourceType.Name()) {
+			if productNames, err = listProductNames(clientOptions); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	kindRequests := make([][]*listRequest, len(selected))
+	var requests []*listRequest
+	for i, value := range selected {
+		if getOptions.AllProducts && isProductScoped(value.ResourceType.Name()) {
+			kindRequests[i] = newProductRequests(value, productNames)
+		} else {
+			kindRequests[i] = []*listRequest{{resourceType: value, product: getOptions.Product}}
+		}
+		requests = append(requests, kindRequests[i]...)
+	}
+	listInParallel(clientOptions, requests)
+
+	results := make([]kindItems, len(selected))
+	for i, value := range selected {
+		results[i].resourceType = value
+		allProducts := getOptions.AllProducts && isProductScoped(value.ResourceType.Name())
+		if results[i].items, results[i].products, err = mergeListRequests(kindRequests[i], allProducts); err != nil {
+			return err
+		}
+		if results[i].items, results[i].products, err = selector.apply(results[i].items, results[i].products); err != nil {
+			return err
+		}
+	}
+
+	switch getOptions.Output {
+	case OutputYaml, OutputJson:
+		items := make([]kindItem, 0)
+		for _, result := range results {
+			for _, item := range result.items {
+				items = append(items, kindItem{APIVersion: ResourceAPIVersion, Kind: result.resourceType.ResourceType.Name(), Spec: item.Interface()})
+			}
+		}
+		return PrintResourceResponseList(items, getOptions.Output, false)
+	default:
+		if tableOptions != nil {
+			return printKindTables(getOptions.Out, results, tableOptions, getOptions.Product, getOptions.AllProducts)
+		}
+		if printItem == nil {
+			return fmt.Errorf("unknown output format: %s", getOptions.Output)
+		}
+		for _, result := range results {
+			for _, item := range result.items {
+				if err = printItem(getOptions.Out, result.resourceType.ResourceType.Name(), item.Interface()); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// printKindTables prints a table with a kind header for every kind with resources,
+// the tables of the kinds listed in every product have a product column.
+func printKindTables(out io.Writer, results []kindItems, tableOptions *tableOptions, product string, allProducts bool) error {
+	var printed int
+	for _, result := range results {
+		if len(result.items) == 0 {
+			continue
+		}
+		if printed > 0 {
+			fmt.Fprintln(out)
+		}
+		printed++
+		fmt.Fprintf(out, "%s:\n", result.resourceType.ResourceType.Name())
+		table, err := tableOptions.generateTable(result.items, result.products, result.resourceType.ResponseItemType)
+		if err != nil {
+			return err
+		}
+		if err = printers.PrintTable(table, out); err != nil {
+			return err
+		}
+	}
+	switch {
+	case printed > 0:
+	case allProducts:
+		fmt.Fprintln(out, "No resources found in any product.")
+	default:
+		fmt.Fprintf(out, "No resources found in product %s.\n", product)
+	}
+	return nil
+}
+
+// resolveKinds returns the types of the kinds named by their command names separated by commas, in the order of the names.
+// KindsAll is every product-scoped kind in the order of the given types.
+func resolveKinds(kinds string, resourceTypeArr []types.ResourcesType) ([]types.ResourcesType, error) {
+	var selected []types.ResourcesType
+	seen := make(map[reflect.Type]bool)
+	add := func(value types.ResourcesType) {
+		if !seen[value.ResourceType] {
+			seen[value.ResourceType] = true
+			selected = append(selected, value)
+		}
+	}
+
+	for _, name := range strings.Split(kinds, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == KindsAll {
+			for _, value := range resourceTypeArr {
+				if isProductScoped(value.ResourceType.Name()) {
+					add(value)
+				}
+			}
+			continue
+		}
+		value, ok := findKind(name, resourceTypeArr)
+		if !ok {
+			return nil, fmt.Errorf("unknown resource type %q", name)
+		}
+		add(value)
+	}
+	return selected, nil
+}
+
+// findKind returns the type whose command names include the name.
+func findKind(name string, resourceTypeArr []types.ResourcesType) (types.ResourcesType, bool) {
+	for _, value := range resourceTypeArr {
+		for _, commandName := range resourceCommandNames(value.ResourceType) {
+			if commandName != "" && commandName == name {
+				return value, true
+			}
+		}
+	}
+	return types.ResourcesType{}, false
+}
+
+// listProductNames lists the names of the products.
+func listProductNames(clientOptions *types.ClientOptions) ([]string, error) {
+	resourceHandler := newResourceHandler(reflect.TypeOf(types.Product{}), "", "")
+	items, err := listResources(clientOptions, resourceHandler, reflect.TypeOf(types.ProductResponseItem{}), io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, reflect.Indirect(item).FieldByName("Name").String())
+	}
+	return names, nil
+}
+
+// listAllProducts lists the resources of a product-scoped kind in every product of the names,
+// and returns them in the order of the products with the product of every resource.
+func listAllProducts(clientOptions *types.ClientOptions, productNames []string, value types.ResourcesType) ([]reflect.Value, []string, error) {
+	requests := newProductRequests(value, productNames)
+	listInParallel(clientOptions, requests)
+	return mergeListRequests(requests, true)
+}
+
+// listRequest lists the resources of a kind in a product, the product is empty for the kinds that are not product-scoped.
+type listRequest struct {
+	resourceType types.ResourcesType
+	product      string
+	items        []reflect.Value
+	err          error
+}
+
+// newProductRequests returns a request listing the resources of the kind for every product.
+func newProductRequests(value types.ResourcesType, productNames []string) []*listRequest {
+	requests := make([]*listRequest, 0, len(productNames))
+	for _, product := range productNames {
+		requests = append(requests, &listRequest{resourceType: value, product: product})
+	}
+	return requests
+}
+
+// listInParallel sends the list requests, listParallelism of them at the same time.
+func listInParallel(clientOptions *types.ClientOptions, requests []*listRequest) {
+	queue := make(chan *listRequest, len(requests))
+	for _, request := range requests {
+		queue <- request
+	}
+	close(queue)
+
+	var wg sync.WaitGroup
+	for i := 0; i < listParallelism && i < len(requests); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for request := range queue {
+				resourceHandler := newResourceHandler(request.resourceType.ResourceType, request.product, "")
+				request.items, request.err = listResources(clientOptions, resourceHandler, request.resourceType.ResponseItemType, io.Discard)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// mergeListRequests returns the resources of the requests in their order, with the product of every resource
+// when the requests list the resources of every product.
+func mergeListRequests(requests []*listRequest, withProducts bool) ([]reflect.Value, []string, error) {
+	var items []reflect.Value
+	var products []string
+	if withProducts {
+		products = make([]string, 0)
+	}
+	for _, request := range requests {
+		if request.err != nil {
+			if withProducts {
+				return nil, nil, fmt.Errorf("product %s: %w", request.product, request.err)
+			}
+			return nil, nil, request.err
+		}
+		items = append(items, request.items...)
+		if withProducts {
+			for range request.items {
+				products = append(products, request.product)
+			}
+		}
+	}
+	return items, products, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
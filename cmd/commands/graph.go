@@ -0,0 +1,290 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/types"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// resourceRef identifies a resource by its kind, product and name, Cluster and Product have no product.
+type resourceRef struct {
+	Kind    string
+	Product string
+	Name    string
+}
+
+func (r resourceRef) String() string {
+	title := fmt.Sprintf("%s '%s'", r.Kind, r.Name)
+	if r.Product != "" {
+		title = fmt.Sprintf("%s of product '%s'", title, r.Product)
+	}
+	return title
+}
+
+// resourceNode is a resource document of the file in the dependency graph.
+type resourceNode struct {
+	resourceType reflect.Type
+	document     *resourceDocument
+	handler      types.ResourceHandler
+	// err is the error decoding the resource.
+	err error
+	// dependsOn are the resources of the file the resource refers to.
+	dependsOn []*resourceNode
+	// dependents are the resources of the file referring to the resource.
+	dependents []*resourceNode
+}
+
+// fieldRef is a reference held by a field of a spec, the path of the field is in the YAML field names of the manifest.
+type fieldRef struct {
+	Path string
+	Ref  resourceRef
+}
+
+// missingRef is a reference to a resource that is not declared in the file.
+type missingRef struct {
+	node *resourceNode
+	fieldRef
+}
+
+// resourceGraph is the dependency graph of the resources of a file, built from the fields of the specs tagged with the kind they refer to.
+type resourceGraph struct {
+	// nodes are in the order of the given types, then in the order of the file.
+	nodes   []*resourceNode
+	missing []missingRef
+}
+
+// buildResourceGraph decodes the resources of the file and links every resource to the resources it refers to.
+// A resource that can not be decoded has no references, the decoding error is returned when the resource is sent.
+func buildResourceGraph(resourcesMap map[string][]*resourceDocument, resourceTypeArr []types.ResourcesType) *resourceGraph {
+	graph := &resourceGraph{}
+	declared := make(map[resourceRef]*resourceNode)
+	for _, value := range resourceTypeArr {
+		typeName := value.ResourceType.Name()
+		for _, document := range resourcesMap[typeName] {
+			resourceObj := reflect.New(value.ResourceType).Interface().(types.ResourceHandler)
+			node := &resourceNode{resourceType: value.ResourceType, document: document, handler: resourceObj}
+			node.err = decodeResource(document.Node, resourceObj)
+			graph.nodes = append(graph.nodes, node)
+			declared[resourceRef{Kind: typeName, Product: getResourceProduct(resourceObj), Name: getSpecField(resourceObj, "Name")}] = node
+		}
+	}
+
+	for _, node := range graph.nodes {
+		linked := make(map[*resourceNode]struct{})
+		for _, ref := range resourceRefs(node.handler) {
+			target, ok := declared[ref.Ref]
+			if !ok {
+				graph.missing = append(graph.missing, missingRef{node: node, fieldRef: ref})
+				continue
+			}
+			if _, ok := linked[target]; ok || target == node {
+				continue
+			}
+			linked[target] = struct{}{}
+			node.dependsOn = append(node.dependsOn, target)
+			target.dependents = append(target.dependents, node)
+		}
+	}
+	return graph
+}
+
+// resourceRefs returns the references held by the fields of the spec, a reference to a product-scoped kind is in the product of the resource,
+// unless the field is tagged with the field holding the product of its references, such as the projects of a code repo binding.
+func resourceRefs(resourceHandler types.ResourceHandler) []fieldRef {
+	var refs []fieldRef
+	collectRefs(reflect.ValueOf(getSpec(resourceHandler)), "", getResourceProduct(resourceHandler), &refs)
+	return refs
+}
+
+func collectRefs(value reflect.Value, path string, product string, refs *[]fieldRef) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			collectRefs(value.Elem(), path, product, refs)
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			collectRefs(value.Index(i), fmt.Sprintf("%s[%d]", path, i), product, refs)
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			fieldPath := yamlFieldName(field)
+			if path != "" {
+				fieldPath = path + "." + fieldPath
+			}
+			kind, ok := field.Tag.Lookup(types.Ref)
+			if !ok {
+				collectRefs(value.Field(i), fieldPath, product, refs)
+				continue
+			}
+			refProduct := product
+			if productField, ok := field.Tag.Lookup(types.RefProduct); ok {
+				if name := value.FieldByName(productField).String(); name != "" {
+					refProduct = name
+				}
+			}
+			collectRefNames(value.Field(i), fieldPath, resourceRef{Kind: kind}, refProduct, refs)
+		}
+	}
+}
+
+// collectRefNames adds the names held by a reference field, which is a string or a list of strings.
+func collectRefNames(value reflect.Value, path string, ref resourceRef, product string, refs *[]fieldRef) {
+	switch value.Kind() {
+	case reflect.String:
+		if value.String() == "" {
+			return
+		}
+		ref.Name = value.String()
+		if isProductScoped(ref.Kind) {
+			ref.Product = product
+		}
+		*refs = append(*refs, fieldRef{Path: path, Ref: ref})
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			collectRefNames(value.Index(i), fmt.Sprintf("%s[%d]", path, i), ref, product, refs)
+		}
+	}
+}
+
+// yamlFieldName returns the name of the struct field in a manifest.
+func yamlFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// tiers sorts the resources topologically into tiers, the resources of a tier only depend on resources of earlier tiers.
+// With reverse a resource comes after the resources referring to it, which is the order the resources are removed in.
+// The resources of a tier are in the order of the given types.
+func (g *resourceGraph) tiers(reverse bool) ([][]*resourceNode, error) {
+	pending := make(map[*resourceNode]int, len(g.nodes))
+	for _, node := range g.nodes {
+		pending[node] = len(node.waitsFor(reverse))
+	}
+
+	var tiers [][]*resourceNode
+	placed := make(map[*resourceNode]bool, len(g.nodes))
+	for len(placed) < len(g.nodes) {
+		var tier []*resourceNode
+		for _, node := range g.nodes {
+			if !placed[node] && pending[node] == 0 {
+				tier = append(tier, node)
+			}
+		}
+		if len(tier) == 0 {
+			return nil, g.cycleError(placed, reverse)
+		}
+		for _, node := range tier {
+			placed[node] = true
+			for _, next := range node.unblocks(reverse) {
+				pending[next]--
+			}
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+func (n *resourceNode) waitsFor(reverse bool) []*resourceNode {
+	if reverse {
+		return n.dependents
+	}
+	return n.dependsOn
+}
+
+func (n *resourceNode) unblocks(reverse bool) []*resourceNode {
+	if reverse {
+		return n.dependsOn
+	}
+	return n.dependents
+}
+
+// cycleError follows the resources that could not be placed in a tier until one of them repeats,
+// and returns an error listing the resources of the cycle.
+func (g *resourceGraph) cycleError(placed map[*resourceNode]bool, reverse bool) error {
+	var start *resourceNode
+	for _, node := range g.nodes {
+		if !placed[node] {
+			start = node
+			break
+		}
+	}
+
+	var path []*resourceNode
+	visited := make(map[*resourceNode]int)
+	node := start
+	for {
+		if idx, ok := visited[node]; ok {
+			path = path[idx:]
+			break
+		}
+		visited[node] = len(path)
+		path = append(path, node)
+		for _, next := range node.waitsFor(reverse) {
+			if !placed[next] {
+				node = next
+				break
+			}
+		}
+	}
+
+	// The cycle is printed in the direction of the references.
+	if reverse {
+		for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+			path[i], path[j] = path[j], path[i]
+		}
+	}
+	titles := make([]string, 0, len(path)+1)
+	for _, node := range path {
+		titles = append(titles, fmt.Sprintf("%s [%s]", describeResource(node.handler), node.document.position()))
+	}
+	titles = append(titles, titles[0])
+	return fmt.Errorf("dependency cycle between the resources of the file: %s", strings.Join(titles, " -> "))
+}
+
+// printMissingRefs prints the resources that are referred to but not declared in the file, with the resources referring to them.
+// They must exist on the API server.
+func printMissingRefs(out io.Writer, graph *resourceGraph) {
+	if len(graph.missing) == 0 {
+		return
+	}
+
+	var refs []resourceRef
+	referrers := make(map[resourceRef][]string)
+	for _, missing := range graph.missing {
+		if _, ok := referrers[missing.Ref]; !ok {
+			refs = append(refs, missing.Ref)
+		}
+		// A resource can refer to the same resource from several fields.
+		title := fmt.Sprintf("%s [%s]", describeResource(missing.node.handler), missing.node.document.position())
+		if n := len(referrers[missing.Ref]); n == 0 || referrers[missing.Ref][n-1] != title {
+			referrers[missing.Ref] = append(referrers[missing.Ref], title)
+		}
+	}
+
+	fmt.Fprintln(out, "Warning: the following resources are not declared in the file and must exist on the API server:")
+	for _, ref := range refs {
+		fmt.Fprintf(out, "  - %s, referred to by %s\n", ref, strings.Join(referrers[ref], ", "))
+	}
+	fmt.Fprintln(out)
+}
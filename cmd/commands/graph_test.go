@@ -0,0 +1,301 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"github.com/nautes-labs/cli/cmd/types"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// testResourceTypes are the resource types in their apply order, like the types of the apply command.
+var testResourceTypes = []types.ResourcesType{
+	{ResourceType: reflect.TypeOf(types.Cluster{}), ResponseItemType: reflect.TypeOf(types.ClusterResponseItem{})},
+	{ResourceType: reflect.TypeOf(types.Product{}), ResponseItemType: reflect.TypeOf(types.ProductResponseItem{})},
+	{ResourceType: reflect.TypeOf(types.Environment{}), ResponseItemType: reflect.TypeOf(types.EnvironmentResponseItem{})},
+	{ResourceType: reflect.TypeOf(types.Project{}), ResponseItemType: reflect.TypeOf(types.ProjectResponseItem{})},
+	{ResourceType: reflect.TypeOf(types.CodeRepo{}), ResponseItemType: reflect.TypeOf(types.CodeRepoResponseItem{})},
+	{ResourceType: reflect.TypeOf(types.CodeRepoBinding{}), ResponseItemType: reflect.TypeOf(types.CodeRepoBindingResponseItem{})},
+	{ResourceType: reflect.TypeOf(types.ProjectPipelineRuntime{}), ResponseItemType: reflect.TypeOf(types.ProjectPipelineRuntimeResponseItem{})},
+	{ResourceType: reflect.TypeOf(types.DeploymentRuntime{}), ResponseItemType: reflect.TypeOf(types.DeploymentRuntimeResponseItem{})},
+}
+
+// testFilePattern matches the path of the temporary file of a manifest.
+var testFilePattern = regexp.MustCompile(`[^ \[]*/test\.yaml`)
+
+// testResourcesMap decodes the documents of the manifest grouped by kind.
+func testResourcesMap(t *testing.T, manifest string) map[string][]*resourceDocument {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "test.yaml")
+	if err := os.WriteFile(filePath, []byte(manifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+	resourcesMap, err := loadResourcesMap(&FileOptions{Filenames: []string{filePath}}, testResourceTypes, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resourcesMap
+}
+
+// tierNames returns the resources of every tier as Kind/name.
+func tierNames(tiers [][]*resourceNode) [][]string {
+	names := make([][]string, 0, len(tiers))
+	for _, tier := range tiers {
+		tierNames := make([]string, 0, len(tier))
+		for _, node := range tier {
+			tierNames = append(tierNames, node.handler.GetKind()+"/"+getSpecField(node.handler, "Name"))
+		}
+		names = append(names, tierNames)
+	}
+	return names
+}
+
+func TestTiersOfExamples(t *testing.T) {
+	fileOptions := &FileOptions{
+		Filenames: []string{"../../examples/demo-product.yaml", "../../examples/demo-pipeline.yaml", "../../examples/demo-deployment.yaml"},
+		Strict:    true,
+		Vars:      []string{"suffix=1", "pipeline-runtime-cluster=c1", "deployment-runtime-cluster=c1"},
+	}
+	resourcesMap, err := loadResourcesMap(fileOptions, testResourceTypes, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := buildResourceGraph(resourcesMap, testResourceTypes)
+
+	tests := []struct {
+		name    string
+		reverse bool
+		want    [][]string
+	}{
+		{
+			name: "apply",
+			want: [][]string{
+				{"Product/demo-1"},
+				{"Environment/env-dev-demo-1", "Environment/env-test-demo-1", "Project/project-demo-1", "CodeRepo/coderepo-deploy-demo-1"},
+				{"CodeRepo/coderepo-sc-demo-1", "CodeRepo/coderepo-pipeline-demo-1", "CodeRepoBinding/coderepobinding-deploy-pipeline-demo-1",
+					"CodeRepoBinding/coderepobinding-deploy-dr-demo-1", "DeploymentRuntime/dr-demo-1"},
+				{"ProjectPipelineRuntime/pr-demo-1"},
+			},
+		},
+		{
+			name:    "remove",
+			reverse: true,
+			want: [][]string{
+				{"CodeRepoBinding/coderepobinding-deploy-pipeline-demo-1", "CodeRepoBinding/coderepobinding-deploy-dr-demo-1",
+					"ProjectPipelineRuntime/pr-demo-1", "DeploymentRuntime/dr-demo-1"},
+				{"Environment/env-dev-demo-1", "Environment/env-test-demo-1", "CodeRepo/coderepo-sc-demo-1",
+					"CodeRepo/coderepo-deploy-demo-1", "CodeRepo/coderepo-pipeline-demo-1"},
+				{"Project/project-demo-1"},
+				{"Product/demo-1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiers, err := graph.tiers(tt.reverse)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tierNames(tiers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tiers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		reverse  bool
+		want     [][]string
+		wantErr  string
+	}{
+		{
+			name: "references before the kind order",
+			manifest: `
+kind: Cluster
+spec:
+  name: vcluster
+  hostCluster: host
+---
+kind: Cluster
+spec:
+  name: host
+`,
+			want: [][]string{{"Cluster/host"}, {"Cluster/vcluster"}},
+		},
+		{
+			name: "references removed first",
+			manifest: `
+kind: Cluster
+spec:
+  name: vcluster
+  hostCluster: host
+---
+kind: Cluster
+spec:
+  name: host
+`,
+			reverse: true,
+			want:    [][]string{{"Cluster/vcluster"}, {"Cluster/host"}},
+		},
+		{
+			name: "resources of other products are not linked",
+			manifest: `
+kind: Project
+spec:
+  name: project
+  product: demo-1
+---
+kind: CodeRepo
+spec:
+  name: repo
+  product: demo-2
+  project: project
+`,
+			want: [][]string{{"Project/project", "CodeRepo/repo"}},
+		},
+		{
+			name: "code repo binding granting projects of another product",
+			manifest: `
+kind: CodeRepoBinding
+spec:
+  name: binding
+  productName: demo-1
+  coderepo: repo
+  product: demo-2
+  projects:
+    - project
+---
+kind: Project
+spec:
+  name: project
+  product: demo-2
+`,
+			want: [][]string{{"Project/project"}, {"CodeRepoBinding/binding"}},
+		},
+		{
+			name: "self reference",
+			manifest: `
+kind: Cluster
+spec:
+  name: host
+  hostCluster: host
+`,
+			want: [][]string{{"Cluster/host"}},
+		},
+		{
+			name: "cycle",
+			manifest: `
+kind: Cluster
+spec:
+  name: a
+  hostCluster: b
+---
+kind: Cluster
+spec:
+  name: b
+  hostCluster: a
+`,
+			wantErr: "dependency cycle between the resources of the file: Cluster 'a' [test.yaml:1 (document 1)] -> " +
+				"Cluster 'b' [test.yaml:6 (document 2)] -> Cluster 'a' [test.yaml:1 (document 1)]",
+		},
+		{
+			name: "cycle in the remove order",
+			manifest: `
+kind: Cluster
+spec:
+  name: a
+  hostCluster: b
+---
+kind: Cluster
+spec:
+  name: b
+  hostCluster: c
+---
+kind: Cluster
+spec:
+  name: c
+  hostCluster: b
+`,
+			reverse: true,
+			wantErr: "dependency cycle between the resources of the file: Cluster 'c' [test.yaml:11 (document 3)] -> " +
+				"Cluster 'b' [test.yaml:6 (document 2)] -> Cluster 'c' [test.yaml:11 (document 3)]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := buildResourceGraph(testResourcesMap(t, strings.TrimPrefix(tt.manifest, "\n")), testResourceTypes)
+			tiers, err := graph.tiers(tt.reverse)
+			if tt.wantErr != "" {
+				// The positions are in the temporary file of the manifest.
+				if err == nil || testFilePattern.ReplaceAllString(err.Error(), "test.yaml") != tt.wantErr {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tierNames(tiers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tiers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingRefs(t *testing.T) {
+	graph := buildResourceGraph(testResourcesMap(t, `kind: Environment
+spec:
+  name: env
+  product: demo
+  cluster: host
+---
+kind: CodeRepoBinding
+spec:
+  name: binding
+  productName: demo
+  coderepo: repo
+  product: demo-2
+  projects:
+    - project
+`), testResourceTypes)
+
+	var got []string
+	for _, missing := range graph.missing {
+		got = append(got, missing.Path+" -> "+missing.Ref.String())
+	}
+	want := []string{"product -> Product 'demo'", "cluster -> Cluster 'host'",
+		"productName -> Product 'demo'", "product -> Product 'demo-2'",
+		"coderepo -> CodeRepo 'repo' of product 'demo'", "projects[0] -> Project 'project' of product 'demo-2'"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("missing = %v, want %v", got, want)
+	}
+
+	var out strings.Builder
+	printMissingRefs(&out, graph)
+	wantOut := `Warning: the following resources are not declared in the file and must exist on the API server:
+  - Product 'demo', referred to by Environment 'env' of product 'demo' [test.yaml:1 (document 1)], CodeRepoBinding 'binding' of product 'demo' [test.yaml:7 (document 2)]
+`
+	if got := testFilePattern.ReplaceAllString(out.String(), "test.yaml"); !strings.HasPrefix(got, wantOut) {
+		t.Errorf("output = %s, want prefix %s", got, wantOut)
+	}
+}
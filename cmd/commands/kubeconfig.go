@@ -0,0 +1,327 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/config"
+	"github.com/nautes-labs/cli/cmd/types"
+	"gopkg.in/yaml.v3"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// EnvKubeconfig is the environment variable listing the default kubeconfig files.
+	EnvKubeconfig = "KUBECONFIG"
+
+	defaultKubeconfigPath = "~/.kube/config"
+)
+
+// kubeconfigFileFields are the fields of the clusters and users of a kubeconfig referring to a file,
+// and the fields their content is embedded into.
+var kubeconfigFileFields = map[string]string{
+	"certificate-authority": "certificate-authority-data",
+	"client-certificate":    "client-certificate-data",
+	"client-key":            "client-key-data",
+}
+
+// kubeconfig is the part of a kubeconfig file the CLI reads, the settings of the clusters and users are kept as they are.
+type kubeconfig struct {
+	APIVersion     string              `yaml:"apiVersion"`
+	Kind           string              `yaml:"kind"`
+	Clusters       []kubeconfigCluster `yaml:"clusters"`
+	Contexts       []kubeconfigContext `yaml:"contexts"`
+	Users          []kubeconfigUser    `yaml:"users"`
+	CurrentContext string              `yaml:"current-context"`
+}
+
+type kubeconfigCluster struct {
+	Name    string                 `yaml:"name"`
+	Cluster map[string]interface{} `yaml:"cluster"`
+}
+
+type kubeconfigContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster   string `yaml:"cluster"`
+		User      string `yaml:"user"`
+		Namespace string `yaml:"namespace,omitempty"`
+	} `yaml:"context"`
+}
+
+type kubeconfigUser struct {
+	Name string                 `yaml:"name"`
+	User map[string]interface{} `yaml:"user"`
+}
+
+// resolveKubeconfigs replaces the kubeconfigFrom of the clusters of the documents with the kubeconfig it loads.
+func resolveKubeconfigs(documents []*resourceDocument) error {
+	for _, document := range documents {
+		source, err := kubeconfigSource(document)
+		if source == nil && err == nil {
+			continue
+		}
+		var content []byte
+		if err == nil {
+			content, err = loadKubeconfig(source)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", document.position(), err)
+		}
+
+		spec := mappingValue(document.Node.Content[0], "spec")
+		for i := 0; i+1 < len(spec.Content); i += 2 {
+			if spec.Content[i].Value == "kubeconfigFrom" {
+				spec.Content = append(spec.Content[:i], spec.Content[i+2:]...)
+				break
+			}
+		}
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.LiteralStyle, Value: string(content)}
+		if kubeconfigNode := mappingValue(spec, "kubeconfig"); kubeconfigNode != nil {
+			*kubeconfigNode = *value
+		} else {
+			spec.Content = append(spec.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "kubeconfig"}, value)
+		}
+	}
+	return nil
+}
+
+// checkKubeconfigSources checks the kubeconfigFrom of the clusters of the documents without loading them,
+// so that no command of the files is run and no credential is read by the commands that do not send the clusters.
+func checkKubeconfigSources(documents []*resourceDocument) error {
+	for _, document := range documents {
+		if _, err := kubeconfigSource(document); err != nil {
+			return fmt.Errorf("%s: %w", document.position(), err)
+		}
+	}
+	return nil
+}
+
+// kubeconfigSource returns the kubeconfigFrom of the spec of a cluster document, or nil if it has none.
+// An error is returned if the cluster also sets its kubeconfig, or if the source does not set exactly one of file, env and exec, or only a context.
+func kubeconfigSource(document *resourceDocument) (*types.KubeconfigSource, error) {
+	if document.Kind != "Cluster" {
+		return nil, nil
+	}
+	spec := mappingValue(document.Node.Content[0], "spec")
+	sourceNode := mappingValue(spec, "kubeconfigFrom")
+	if sourceNode == nil {
+		return nil, nil
+	}
+
+	source := &types.KubeconfigSource{}
+	if err := sourceNode.Decode(source); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfigFrom: %w", err)
+	}
+	if specValue(document, "kubeconfig") != "" {
+		return nil, fmt.Errorf("kubeconfig and kubeconfigFrom cannot be both set")
+	}
+	var sources int
+	for _, value := range []string{source.File, source.Env, source.Exec} {
+		if value != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, fmt.Errorf("kubeconfigFrom must set only one of file, env and exec")
+	}
+	if sources == 0 && source.Context == "" {
+		return nil, fmt.Errorf("kubeconfigFrom must set one of file, env, exec and context")
+	}
+	return source, nil
+}
+
+// loadKubeconfig loads the kubeconfig of the source, reduced to the context of the source if it has one.
+func loadKubeconfig(source *types.KubeconfigSource) ([]byte, error) {
+	var content []byte
+	// dir is the directory the relative file paths of the kubeconfig are resolved against.
+	var dir string
+	var err error
+	switch {
+	case source.Env != "":
+		value, ok := os.LookupEnv(source.Env)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("the environment variable %s of the kubeconfig is not set", source.Env)
+		}
+		content = []byte(value)
+	case source.Exec != "":
+		content, err = execKubeconfig(source.Exec)
+	default:
+		path := source.File
+		if path == "" {
+			path = defaultKubeconfigFile()
+		}
+		path = config.ExpandHome(path)
+		dir = filepath.Dir(path)
+		content, err = os.ReadFile(path)
+		if err != nil {
+			err = fmt.Errorf("error reading kubeconfig: %w", err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if source.Context == "" {
+		return content, nil
+	}
+	return minifyKubeconfig(content, source.Context, dir)
+}
+
+// defaultKubeconfigFile returns the first file of $KUBECONFIG, or ~/.kube/config.
+func defaultKubeconfigFile() string {
+	for _, path := range filepath.SplitList(os.Getenv(EnvKubeconfig)) {
+		if path != "" {
+			return path
+		}
+	}
+	return defaultKubeconfigPath
+}
+
+// execKubeconfig runs the command with the shell and returns what it prints to stdout.
+func execKubeconfig(command string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			err = fmt.Errorf("%w: %s", err, message)
+		}
+		return nil, fmt.Errorf("failed to run kubeconfig command %q: %w", command, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// minifyKubeconfig returns a kubeconfig holding only the context, its cluster and its user, with the context as current context.
+// An empty context is the current context of the kubeconfig.
+// The files the cluster and the user refer to are embedded, relative paths are resolved against dir.
+func minifyKubeconfig(content []byte, contextName, dir string) ([]byte, error) {
+	var full kubeconfig
+	if err := yaml.Unmarshal(content, &full); err != nil {
+		return nil, fmt.Errorf("error unmarshaling kubeconfig: %w", err)
+	}
+	if contextName == "" {
+		contextName = full.CurrentContext
+	}
+	if contextName == "" {
+		return nil, fmt.Errorf("the kubeconfig has no current context, set the context")
+	}
+
+	minified := kubeconfig{
+		APIVersion:     full.APIVersion,
+		Kind:           full.Kind,
+		CurrentContext: contextName,
+	}
+	for _, context := range full.Contexts {
+		if context.Name == contextName {
+			minified.Contexts = append(minified.Contexts, context)
+			break
+		}
+	}
+	if len(minified.Contexts) == 0 {
+		return nil, fmt.Errorf("context %s not found in kubeconfig", contextName)
+	}
+	context := minified.Contexts[0]
+
+	for _, cluster := range full.Clusters {
+		if cluster.Name == context.Context.Cluster {
+			minified.Clusters = append(minified.Clusters, cluster)
+			break
+		}
+	}
+	if len(minified.Clusters) == 0 {
+		return nil, fmt.Errorf("cluster %s of context %s not found in kubeconfig", context.Context.Cluster, contextName)
+	}
+	for _, user := range full.Users {
+		if user.Name == context.Context.User {
+			minified.Users = append(minified.Users, user)
+			break
+		}
+	}
+	if len(minified.Users) == 0 && context.Context.User != "" {
+		return nil, fmt.Errorf("user %s of context %s not found in kubeconfig", context.Context.User, contextName)
+	}
+
+	if err := embedKubeconfigFiles(minified.Clusters[0].Cluster, dir); err != nil {
+		return nil, fmt.Errorf("cluster %s: %w", minified.Clusters[0].Name, err)
+	}
+	for _, user := range minified.Users {
+		if err := embedKubeconfigFiles(user.User, dir); err != nil {
+			return nil, fmt.Errorf("user %s: %w", user.Name, err)
+		}
+	}
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&minified); err != nil {
+		return nil, fmt.Errorf("unable to marshal kubeconfig to yaml: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// kubeconfigServer returns the server of the first cluster of the kubeconfig, the cluster of a minified kubeconfig.
+func kubeconfigServer(content []byte) (string, error) {
+	var minified kubeconfig
+	if err := yaml.Unmarshal(content, &minified); err != nil {
+		return "", fmt.Errorf("error unmarshaling kubeconfig: %w", err)
+	}
+	if len(minified.Clusters) > 0 {
+		if server, _ := minified.Clusters[0].Cluster["server"].(string); server != "" {
+			return server, nil
+		}
+	}
+	return "", fmt.Errorf("the cluster of the kubeconfig has no server")
+}
+
+// embedKubeconfigFiles replaces the fields referring to a file with the content of the file.
+func embedKubeconfigFiles(settings map[string]interface{}, dir string) error {
+	readFile := func(field string) ([]byte, error) {
+		path, _ := settings[field].(string)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", field, err)
+		}
+		delete(settings, field)
+		return content, nil
+	}
+
+	for field, dataField := range kubeconfigFileFields {
+		if _, ok := settings[field]; !ok {
+			continue
+		}
+		content, err := readFile(field)
+		if err != nil {
+			return err
+		}
+		settings[dataField] = base64.StdEncoding.EncodeToString(content)
+	}
+	if _, ok := settings["tokenFile"]; ok {
+		content, err := readFile("tokenFile")
+		if err != nil {
+			return err
+		}
+		settings["token"] = strings.TrimSpace(string(content))
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"encoding/base64"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+  - name: a
+    cluster:
+      server: https://a:6443
+      certificate-authority: ca.crt
+  - name: b
+    cluster:
+      server: https://b:6443
+contexts:
+  - name: a
+    context:
+      cluster: a
+      user: a
+  - name: b
+    context:
+      cluster: b
+      user: b
+users:
+  - name: a
+    user:
+      tokenFile: /token
+  - name: b
+    user:
+      token: b
+current-context: b
+`
+
+func TestMinifyKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ca.crt"), []byte("ca"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("a\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Replace(testKubeconfig, "/token", tokenFile, 1)
+
+	tests := []struct {
+		name       string
+		context    string
+		wantServer string
+		wantFields map[string]interface{}
+		wantErr    string
+	}{
+		{
+			name:       "context with files",
+			context:    "a",
+			wantServer: "https://a:6443",
+			wantFields: map[string]interface{}{
+				"certificate-authority-data": base64.StdEncoding.EncodeToString([]byte("ca")),
+				"token":                      "a",
+			},
+		},
+		{
+			name:       "current context",
+			wantServer: "https://b:6443",
+			wantFields: map[string]interface{}{"token": "b"},
+		},
+		{
+			name:    "unknown context",
+			context: "c",
+			wantErr: "context c not found in kubeconfig",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minified, err := minifyKubeconfig([]byte(content), tt.context, dir)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			server, err := kubeconfigServer(minified)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if server != tt.wantServer {
+				t.Errorf("server = %s, want %s", server, tt.wantServer)
+			}
+
+			var parsed kubeconfig
+			if err = yaml.Unmarshal(minified, &parsed); err != nil {
+				t.Fatal(err)
+			}
+			if len(parsed.Clusters) != 1 || len(parsed.Contexts) != 1 || len(parsed.Users) != 1 {
+				t.Fatalf("minified kubeconfig has %d clusters, %d contexts and %d users, want one of each",
+					len(parsed.Clusters), len(parsed.Contexts), len(parsed.Users))
+			}
+			fields := make(map[string]interface{})
+			for key, value := range parsed.Clusters[0].Cluster {
+				if key != "server" {
+					fields[key] = value
+				}
+			}
+			for key, value := range parsed.Users[0].User {
+				fields[key] = value
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestLoadDocumentsKubeconfigFrom(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	manifest := filepath.Join(dir, "cluster.yaml")
+	content := fmt.Sprintf(`kind: Cluster
+spec:
+  name: host
+  kubeconfigFrom:
+    exec: touch %s && echo kubeconfig
+`, marker)
+	if err := os.WriteFile(manifest, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		resolve        bool
+		wantKubeconfig string
+		wantRun        bool
+	}{
+		{name: "render and diff", resolve: false},
+		{name: "apply", resolve: true, wantKubeconfig: "kubeconfig\n", wantRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(marker)
+			documents, err := loadDocuments(&FileOptions{Filenames: []string{manifest}, ResolveKubeconfig: tt.resolve}, testResourceTypes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := specValue(documents[0], "kubeconfig"); got != tt.wantKubeconfig {
+				t.Errorf("kubeconfig = %q, want %q", got, tt.wantKubeconfig)
+			}
+			if _, err = os.Stat(marker); (err == nil) != tt.wantRun {
+				t.Errorf("command run = %t, want %t", err == nil, tt.wantRun)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+// specDefault is a value the API server fills in when a field of the spec is not set.
+type specDefault struct {
+	path string
+	// value is the default value of the field.
+	value string
+	// fromPath is the field whose value is copied when value is empty, such as the name of the runtime for its account.
+	fromPath string
+}
+
+// specDefaults are the defaults of the API server, keyed by resource kind.
+var specDefaults = map[string][]specDefault{
+	"Cluster": {
+		{path: "clusterKind", value: "kubernetes"},
+	},
+	"ProjectPipelineRuntime": {
+		{path: "account", fromPath: "name"},
+		{path: "isolation", value: "shared"},
+	},
+	"DeploymentRuntime": {
+		{path: "account", fromPath: "name"},
+	},
+}
+
+// outputOnlyFields are the fields set by the API server which can not be declared in a manifest, keyed by resource kind.
+var outputOnlyFields = map[string][]string{
+	"CodeRepo": {"git.gitlab.sshUrlToRepo", "git.gitlab.httpUrlToRepo"},
+}
+
+// writeOnlyFields are the fields that the API server does not return, such as credentials, keyed by resource kind.
+// They can not be compared when the live resource does not have them, so a declared value is always a change.
+var writeOnlyFields = map[string][]string{
+	"Cluster": {"kubeconfig"},
+}
+
+const (
+	// writeOnlyLiveValue is the live value of a write-only field that the API server does not return.
+	writeOnlyLiveValue = "(not returned by the API server)"
+	// writeOnlyDesiredValue replaces the declared value of a write-only field, so that credentials are not printed.
+	writeOnlyDesiredValue = "(declared, always sent)"
+	// unloadedKubeconfig is the kubeconfig of a cluster declaring a kubeconfigFrom, which diff does not load.
+	unloadedKubeconfig = "(loaded from kubeconfigFrom by apply)"
+)
+
+// normalizeFields removes the fields of the flattened live and desired specs that can not be compared,
+// and fills in the defaults of the API server on both sides.
+func normalizeFields(kind string, liveFields, desiredFields map[string]string) {
+	for _, path := range outputOnlyFields[kind] {
+		delete(liveFields, path)
+		delete(desiredFields, path)
+	}
+	for _, path := range writeOnlyFields[kind] {
+		_, returned := liveFields[path]
+		if _, declared := desiredFields[path]; returned || !declared {
+			continue
+		}
+		// A resource that does not exist has no live value.
+		if len(liveFields) > 0 {
+			liveFields[path] = writeOnlyLiveValue
+		}
+		desiredFields[path] = writeOnlyDesiredValue
+	}
+	for _, fields := range []map[string]string{liveFields, desiredFields} {
+		// Nothing to fill in for a resource that does not exist.
+		if len(fields) == 0 {
+			continue
+		}
+		for _, def := range specDefaults[kind] {
+			if _, ok := fields[def.path]; ok {
+				continue
+			}
+			value := def.value
+			if def.fromPath != "" {
+				value = fields[def.fromPath]
+			}
+			if value != "" {
+				fields[def.path] = value
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		kind        string
+		live        map[string]string
+		desired     map[string]string
+		wantLive    map[string]string
+		wantDesired map[string]string
+	}{
+		{
+			name:        "kubeconfig not returned is a change",
+			kind:        "Cluster",
+			live:        map[string]string{"name": "host", "clusterKind": "kubernetes"},
+			desired:     map[string]string{"name": "host", "kubeconfig": "secret"},
+			wantLive:    map[string]string{"name": "host", "clusterKind": "kubernetes", "kubeconfig": writeOnlyLiveValue},
+			wantDesired: map[string]string{"name": "host", "clusterKind": "kubernetes", "kubeconfig": writeOnlyDesiredValue},
+		},
+		{
+			name:        "kubeconfig returned is compared",
+			kind:        "Cluster",
+			live:        map[string]string{"name": "host", "kubeconfig": "secret"},
+			desired:     map[string]string{"name": "host", "kubeconfig": "secret"},
+			wantLive:    map[string]string{"name": "host", "clusterKind": "kubernetes", "kubeconfig": "secret"},
+			wantDesired: map[string]string{"name": "host", "clusterKind": "kubernetes", "kubeconfig": "secret"},
+		},
+		{
+			name:        "kubeconfig of a new cluster is not printed",
+			kind:        "Cluster",
+			live:        map[string]string{},
+			desired:     map[string]string{"name": "host", "kubeconfig": "secret"},
+			wantLive:    map[string]string{},
+			wantDesired: map[string]string{"name": "host", "clusterKind": "kubernetes", "kubeconfig": writeOnlyDesiredValue},
+		},
+		{
+			name:        "kubeconfig not declared",
+			kind:        "Cluster",
+			live:        map[string]string{"name": "host"},
+			desired:     map[string]string{"name": "host"},
+			wantLive:    map[string]string{"name": "host", "clusterKind": "kubernetes"},
+			wantDesired: map[string]string{"name": "host", "clusterKind": "kubernetes"},
+		},
+		{
+			name:        "output-only fields and defaults",
+			kind:        "ProjectPipelineRuntime",
+			live:        map[string]string{"name": "pr", "account": "pr", "isolation": "shared"},
+			desired:     map[string]string{"name": "pr"},
+			wantLive:    map[string]string{"name": "pr", "account": "pr", "isolation": "shared"},
+			wantDesired: map[string]string{"name": "pr", "account": "pr", "isolation": "shared"},
+		},
+		{
+			name:        "ssh url of code repo is ignored",
+			kind:        "CodeRepo",
+			live:        map[string]string{"name": "cr", "git.gitlab.sshUrlToRepo": "git@example.com:cr.git"},
+			desired:     map[string]string{"name": "cr"},
+			wantLive:    map[string]string{"name": "cr"},
+			wantDesired: map[string]string{"name": "cr"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalizeFields(tt.kind, tt.live, tt.desired)
+			if !reflect.DeepEqual(tt.live, tt.wantLive) {
+				t.Errorf("live = %v, want %v", tt.live, tt.wantLive)
+			}
+			if !reflect.DeepEqual(tt.desired, tt.wantDesired) {
+				t.Errorf("desired = %v, want %v", tt.desired, tt.wantDesired)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/printers"
+	"github.com/nautes-labs/cli/cmd/types"
+	"gopkg.in/yaml.v3"
+	"io"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/jsonpath"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+const (
+	OutputName           = "name"
+	OutputJSONPath       = "jsonpath"
+	OutputGoTemplate     = "go-template"
+	OutputGoTemplateFile = "go-template-file"
+	OutputCustomColumns  = "custom-columns"
+)
+
+const (
+	// outputFlagUsage is the usage of the output flag of the get commands.
+	outputFlagUsage = "Output format. One of: json|yaml|wide|name|custom-columns=...|jsonpath=...|go-template=...|go-template-file=..."
+	// columnsFlagUsage is the usage of the columns flag of the get commands.
+	columnsFlagUsage = "Columns added to the table such as DESC:.git.gitlab.description, or removed from it such as -product, separated by commas"
+	// noneValue is the value of a custom column whose path finds nothing.
+	noneValue = "<none>"
+	// productColumnName is the name of the column holding the product of the resources listed in every product.
+	productColumnName = "product"
+	// ownerColumnName replaces productColumnName when the product column of the kind is not the product the resources belong to,
+	// such as the product a CodeRepoBinding grants the code repo to.
+	ownerColumnName = "owner"
+)
+
+var errColumnsWithoutTable = errors.New(`flag "columns" only applies to the table output formats`)
+
+// customColumn is a column of the custom-columns output format or of the columns flag, its values are found by a jsonpath.
+type customColumn struct {
+	header string
+	path   *jsonpath.JSONPath
+}
+
+// tableOptions are the columns of the tables printed by the get commands.
+type tableOptions struct {
+	wide bool
+	// customColumns replace the columns of the tags with the custom-columns output format.
+	customColumns []customColumn
+	hidden        []string
+	added         []customColumn
+}
+
+// itemPrinter prints a resource of the kind on its own, for the output formats meant for scripts.
+type itemPrinter func(out io.Writer, kind string, item interface{}) error
+
+// newItemPrinter returns the printer of the output format, or nil for json, yaml and the tables which print the resources together.
+// The templates are evaluated against every resource with the field names of the yaml output, and their result ends with a newline.
+func newItemPrinter(output string) (itemPrinter, error) {
+	format, argument, _ := strings.Cut(output, "=")
+	switch format {
+	case OutputName:
+		if output != OutputName {
+			return nil, fmt.Errorf("unknown output format: %s", output)
+		}
+		return printItemName, nil
+	case OutputJSONPath:
+		if argument == "" {
+			return nil, fmt.Errorf("missing template of output format %s, such as %s='{.name}'", format, format)
+		}
+		// The braces may be left out around a single expression, such as .name.
+		if !strings.Contains(argument, "{") {
+			argument = fmt.Sprintf("{%s}", argument)
+		}
+		parser := jsonpath.New(OutputJSONPath).AllowMissingKeys(true)
+		if err := parser.Parse(argument); err != nil {
+			return nil, fmt.Errorf("invalid jsonpath template %s: %w", argument, err)
+		}
+		return templateItemPrinter(parser.Execute), nil
+	case OutputGoTemplate, OutputGoTemplateFile:
+		if argument == "" {
+			return nil, fmt.Errorf("missing template of output format %s", format)
+		}
+		text := argument
+		if format == OutputGoTemplateFile {
+			content, err := os.ReadFile(argument)
+			if err != nil {
+				return nil, fmt.Errorf("error reading template file: %w", err)
+			}
+			text = string(content)
+		}
+		tmpl, err := template.New(OutputGoTemplate).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid go template: %w", err)
+		}
+		return templateItemPrinter(tmpl.Execute), nil
+	case OutputJson, OutputYaml, OutputWide, OutputCustomColumns, "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", output)
+	}
+}
+
+// templateItemPrinter returns a printer executing a template against the fields of a resource.
+func templateItemPrinter(execute func(io.Writer, interface{}) error) itemPrinter {
+	return func(out io.Writer, _ string, item interface{}) error {
+		fields, err := yamlFields(item)
+		if err != nil {
+			return err
+		}
+		var buffer bytes.Buffer
+		if err = execute(&buffer, fields); err != nil {
+			return fmt.Errorf("error executing template: %w", err)
+		}
+		if buffer.Len() > 0 && !bytes.HasSuffix(buffer.Bytes(), []byte("\n")) {
+			buffer.WriteByte('\n')
+		}
+		_, err = out.Write(buffer.Bytes())
+		return err
+	}
+}
+
+// printItemName prints the resource as kind/name, the kind is in lowercase like the get subcommand of the kind.
+func printItemName(out io.Writer, kind string, item interface{}) error {
+	name := reflect.Indirect(reflect.ValueOf(item)).FieldByName("Name")
+	if !name.IsValid() {
+		return fmt.Errorf("%s has no name", kind)
+	}
+	_, err := fmt.Fprintf(out, "%s/%s\n", strings.ToLower(kind), name.String())
+	return err
+}
+
+// yamlFields converts the resource to the maps and lists of its yaml output.
+func yamlFields(item interface{}) (interface{}, error) {
+	content, err := yaml.Marshal(item)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal resource to yaml: %w", err)
+	}
+	var fields interface{}
+	if err = yaml.Unmarshal(content, &fields); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal resource from yaml: %w", err)
+	}
+	return fields, nil
+}
+
+// newTableOptions returns the columns of the table output format and of the columns flag,
+// or nil when the output format is not a table.
+func newTableOptions(output, columns string) (*tableOptions, error) {
+	format, argument, _ := strings.Cut(output, "=")
+	options := &tableOptions{}
+	switch format {
+	case OutputWide, "":
+		options.wide = output == OutputWide
+	case OutputCustomColumns:
+		if argument == "" {
+			return nil, fmt.Errorf("missing columns of output format %s, such as %s=NAME:.name", format, format)
+		}
+		if columns != "" {
+			return nil, fmt.Errorf(`flag "columns" cannot be used with output format %s`, format)
+		}
+		customColumns, err := parseCustomColumns(strings.Split(argument, ","))
+		if err != nil {
+			return nil, err
+		}
+		options.customColumns = customColumns
+		return options, nil
+	default:
+		if columns != "" {
+			return nil, errColumnsWithoutTable
+		}
+		return nil, nil
+	}
+
+	var added []string
+	for _, column := range strings.Split(columns, ",") {
+		switch {
+		case column == "":
+		case strings.HasPrefix(column, "-"):
+			options.hidden = append(options.hidden, strings.TrimPrefix(column, "-"))
+		default:
+			added = append(added, strings.TrimPrefix(column, "+"))
+		}
+	}
+	var err error
+	options.added, err = parseCustomColumns(added)
+	return options, err
+}
+
+// parseCustomColumns parses the columns, a column is HEADER:PATH or a PATH whose last field is the header.
+// The path is a jsonpath template on the fields of the yaml output, its braces may be left out such as .git.gitlab.path.
+func parseCustomColumns(columns []string) ([]customColumn, error) {
+	customColumns := make([]customColumn, 0, len(columns))
+	for _, column := range columns {
+		header, path, found := strings.Cut(column, ":")
+		if !found {
+			path = column
+			header = path[strings.LastIndex(path, ".")+1:]
+		}
+		if header == "" || path == "" {
+			return nil, fmt.Errorf("invalid column %s, must be HEADER:PATH such as NAME:.name", column)
+		}
+		if !strings.Contains(path, "{") {
+			path = fmt.Sprintf("{%s}", path)
+		}
+		parser := jsonpath.New(header).AllowMissingKeys(true)
+		if err := parser.Parse(path); err != nil {
+			return nil, fmt.Errorf("invalid path of column %s: %w", header, err)
+		}
+		customColumns = append(customColumns, customColumn{header: header, path: parser})
+	}
+	return customColumns, nil
+}
+
+// value returns the values the path of the column finds in the fields separated by commas, or noneValue when it finds none.
+func (c customColumn) value(fields interface{}) (string, error) {
+	results, err := c.path.FindResults(fields)
+	if err != nil {
+		return "", fmt.Errorf("error finding the values of column %s: %w", c.header, err)
+	}
+	var values []string
+	for _, result := range results {
+		for _, value := range result {
+			if value.IsValid() && value.CanInterface() && value.Interface() != nil {
+				values = append(values, fmt.Sprint(value.Interface()))
+			}
+		}
+	}
+	if len(values) == 0 {
+		return noneValue, nil
+	}
+	return strings.Join(values, ","), nil
+}
+
+// extraColumn returns the column of the table of the column tags whose values are found by the path of the column.
+func (c customColumn) extraColumn() printers.ExtraColumn {
+	return printers.ExtraColumn{
+		Name: c.header,
+		Value: func(_ int, value reflect.Value) (string, error) {
+			fields, err := yamlFields(value.Interface())
+			if err != nil {
+				return "", err
+			}
+			return c.value(fields)
+		},
+	}
+}
+
+// generateTable creates the table of the resources, with a product column in front when the resources are listed in every product.
+func (o *tableOptions) generateTable(items []reflect.Value, products []string, responseItemType reflect.Type) (*metav1.Table, error) {
+	if o.customColumns != nil {
+		return customColumnsTable(items, o.customColumns)
+	}
+
+	options := printers.TableOptions{Wide: o.wide, Hidden: o.hidden}
+	if products != nil {
+		// The product column of the item type is left out for the product the resources are listed in, when it holds that product.
+		name := productColumnName
+		switch field := columnField(responseItemType, productColumnName); {
+		case field == "":
+		case field == ownerProductField(responseItemType):
+			options.Hidden = append(options.Hidden, productColumnName)
+		default:
+			name = ownerColumnName
+		}
+		options.Prepended = append(options.Prepended, printers.ExtraColumn{
+			Name: name,
+			Value: func(index int, _ reflect.Value) (string, error) {
+				return products[index], nil
+			},
+		})
+	}
+	for _, column := range o.added {
+		options.Appended = append(options.Appended, column.extraColumn())
+	}
+	return printers.GenerateTableWithOptions(items, responseItemType, options)
+}
+
+// columnField returns the name of the field of the type tagged with the column, or an empty string if there is none.
+func columnField(t reflect.Type, column string) string {
+	for i := 0; i < t.NumField(); i++ {
+		if strings.EqualFold(t.Field(i).Tag.Get(types.Column), column) {
+			return t.Field(i).Name
+		}
+	}
+	return ""
+}
+
+// ownerProductField returns the name of the field holding the product the resources of the type belong to,
+// CodeRepoBinding names it ProductName like setResourceProduct.
+func ownerProductField(t reflect.Type) string {
+	if _, ok := t.FieldByName("ProductName"); ok {
+		return "ProductName"
+	}
+	return "Product"
+}
+
+// customColumnsTable creates the table of the custom columns, with a row for every resource.
+func customColumnsTable(items []reflect.Value, customColumns []customColumn) (*metav1.Table, error) {
+	table := &metav1.Table{}
+	for _, column := range customColumns {
+		table.ColumnDefinitions = append(table.ColumnDefinitions, metav1.TableColumnDefinition{Name: column.header, Type: "string"})
+	}
+	for _, item := range items {
+		fields, err := yamlFields(item.Interface())
+		if err != nil {
+			return nil, err
+		}
+		row := metav1.TableRow{}
+		for _, column := range customColumns {
+			value, err := column.value(fields)
+			if err != nil {
+				return nil, err
+			}
+			row.Cells = append(row.Cells, value)
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
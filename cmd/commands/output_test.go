@@ -0,0 +1,102 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"github.com/nautes-labs/cli/cmd/types"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateTableOfAllProducts(t *testing.T) {
+	tests := []struct {
+		name        string
+		items       []reflect.Value
+		wantColumns []string
+		wantCells   []interface{}
+	}{
+		{
+			name: "owning product column replaced",
+			items: []reflect.Value{reflect.ValueOf(&types.EnvironmentResponseItem{
+				Name: "env", Product: "demo-1", Cluster: "host", EnvType: "test"})},
+			wantColumns: []string{"product", "name", "cluster", "env_type"},
+			wantCells:   []interface{}{"demo-1", "env", "host", "test"},
+		},
+		{
+			name: "granted product column kept",
+			items: []reflect.Value{reflect.ValueOf(&types.CodeRepoBindingResponseItem{
+				Name: "crb", ProductName: "demo-1", Product: "demo-2", CodeRepo: "repo", Permissions: "readonly"})},
+			wantColumns: []string{"owner", "name", "product", "coderepo", "permissions", "projects"},
+			wantCells:   []interface{}{"demo-1", "crb", "demo-2", "repo", "readonly", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products := make([]string, 0, len(tt.items))
+			for _, item := range tt.items {
+				products = append(products, itemProduct(item))
+			}
+			table, err := (&tableOptions{}).generateTable(tt.items, products, tt.items[0].Elem().Type())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var columns []string
+			for _, column := range table.ColumnDefinitions {
+				columns = append(columns, column.Name)
+			}
+			if !reflect.DeepEqual(columns, tt.wantColumns) {
+				t.Errorf("columns = %v, want %v", columns, tt.wantColumns)
+			}
+			if len(table.Rows) == 0 || !reflect.DeepEqual(table.Rows[0].Cells, tt.wantCells) {
+				t.Errorf("rows = %v, want first row %v", table.Rows, tt.wantCells)
+			}
+		})
+	}
+}
+
+// itemProduct returns the product the listed item belongs to.
+func itemProduct(item reflect.Value) string {
+	return reflect.Indirect(item).FieldByName(ownerProductField(item.Elem().Type())).String()
+}
+
+func TestMergeListRequests(t *testing.T) {
+	kind := types.ResourcesType{ResourceType: reflect.TypeOf(types.Environment{}), ResponseItemType: reflect.TypeOf(types.EnvironmentResponseItem{})}
+	item := func(name string) reflect.Value {
+		return reflect.ValueOf(&types.EnvironmentResponseItem{Name: name})
+	}
+	requests := newProductRequests(kind, []string{"demo-1", "demo-2", "demo-3"})
+	requests[0].items = []reflect.Value{item("a"), item("b")}
+	requests[2].items = []reflect.Value{item("c")}
+
+	items, products, err := mergeListRequests(requests, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Elem().FieldByName("Name").String())
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	if want := []string{"demo-1", "demo-1", "demo-3"}; !reflect.DeepEqual(products, want) {
+		t.Errorf("products = %v, want %v", products, want)
+	}
+
+	requests[1].err = errProductNotSet
+	if _, _, err = mergeListRequests(requests, true); err == nil || err.Error() != "product demo-2: "+errProductNotSet.Error() {
+		t.Errorf("error = %v, want the error of product demo-2", err)
+	}
+}
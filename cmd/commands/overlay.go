@@ -0,0 +1,164 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+)
+
+const FlagOverlay = "overlay"
+
+// applyOverlays merges every overlay document into the base document of the same kind and spec name, in the order of the overlays.
+// An overlay that sets the product of the spec only matches the base document of that product.
+func applyOverlays(documents, overlays []*resourceDocument) error {
+	for _, overlay := range overlays {
+		base, err := overlayTarget(documents, overlay)
+		if err != nil {
+			return err
+		}
+		mergeNode(base.Node.Content[0], overlay.Node.Content[0])
+	}
+	return nil
+}
+
+// overlayTarget finds the base document the overlay is merged into.
+func overlayTarget(documents []*resourceDocument, overlay *resourceDocument) (*resourceDocument, error) {
+	name := specValue(overlay, "name")
+	if name == "" {
+		return nil, fmt.Errorf("%s: the overlay has no spec.name", overlay.position())
+	}
+	product := documentProduct(overlay)
+
+	var matches []*resourceDocument
+	for _, document := range documents {
+		if document.Kind != overlay.Kind || specValue(document, "name") != name {
+			continue
+		}
+		if product != "" && documentProduct(document) != product {
+			continue
+		}
+		matches = append(matches, document)
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%s: there is no %s named '%s' to overlay", overlay.position(), overlay.Kind, name)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%s: %d resources of kind %s are named '%s', set the product of the overlay", overlay.position(), len(matches), overlay.Kind, name)
+	}
+}
+
+// documentProduct returns the product of the spec of the document, CodeRepoBinding names it productName.
+func documentProduct(document *resourceDocument) string {
+	if product := specValue(document, "product"); product != "" {
+		return product
+	}
+	return specValue(document, "productName")
+}
+
+// specValue returns the scalar value of a field of the spec of the document.
+func specValue(document *resourceDocument, field string) string {
+	value := mappingValue(mappingValue(document.Node.Content[0], "spec"), field)
+	if value == nil || value.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return value.Value
+}
+
+// mappingValue returns the value of the key of a mapping node, or nil if the node is not a mapping or has no such key.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mergeNode merges the overlay node into the base node and returns the result.
+// Mappings are merged key by key, lists whose items all have a key are merged item by item with the same key,
+// and any other value of the overlay replaces the value of the base.
+func mergeNode(base, overlay *yaml.Node) *yaml.Node {
+	if base == nil || base.Kind != overlay.Kind {
+		return overlay
+	}
+
+	switch overlay.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			key, value := overlay.Content[i], overlay.Content[i+1]
+			merged := false
+			for j := 0; j+1 < len(base.Content); j += 2 {
+				if base.Content[j].Value == key.Value {
+					base.Content[j+1] = mergeNode(base.Content[j+1], value)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				base.Content = append(base.Content, key, value)
+			}
+		}
+		return base
+	case yaml.SequenceNode:
+		if !isKeyed(base) || !isKeyed(overlay) {
+			return overlay
+		}
+		for _, item := range overlay.Content {
+			key := itemKey(item)
+			merged := false
+			for i, baseItem := range base.Content {
+				if itemKey(baseItem) == key {
+					base.Content[i] = mergeNode(baseItem, item)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				base.Content = append(base.Content, item)
+			}
+		}
+		return base
+	default:
+		return overlay
+	}
+}
+
+// isKeyed reports whether every item of the list is a mapping with a key, such as pipelines, eventSources and hooks.
+func isKeyed(node *yaml.Node) bool {
+	for _, item := range node.Content {
+		if itemKey(item) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// itemKey returns the key merging an item of a list, the alias of the item or else its name.
+// A hook is keyed by its alias, since the same hook may run several times with different aliases.
+func itemKey(item *yaml.Node) string {
+	for _, field := range []string{"alias", "name"} {
+		if value := mappingValue(item, field); value != nil && value.Kind == yaml.ScalarNode && value.Value != "" {
+			return value.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+)
+
+func TestMergeNode(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		overlay string
+		want    string
+	}{
+		{
+			name:    "mappings by key",
+			base:    "a: 1\nb:\n  c: 2\n  d: 3\n",
+			overlay: "b:\n  d: 4\ne: 5\n",
+			want:    "a: 1\nb:\n  c: 2\n  d: 4\ne: 5\n",
+		},
+		{
+			name:    "list by name",
+			base:    "pipelines:\n  - name: dev\n    path: dev.yaml\n  - name: test\n    path: test.yaml\n",
+			overlay: "pipelines:\n  - name: test\n    path: other.yaml\n  - name: prod\n    path: prod.yaml\n",
+			want:    "pipelines:\n  - name: dev\n    path: dev.yaml\n  - name: test\n    path: other.yaml\n  - name: prod\n    path: prod.yaml\n",
+		},
+		{
+			name: "hooks by alias",
+			base: "preHooks:\n  - name: ls\n    alias: ls-a\n    vars:\n      path: a\n" +
+				"  - name: ls\n    alias: ls-b\n    vars:\n      path: b\n",
+			overlay: "preHooks:\n  - name: ls\n    alias: ls-b\n    vars:\n      path: c\n",
+			want: "preHooks:\n  - name: ls\n    alias: ls-a\n    vars:\n      path: a\n" +
+				"  - name: ls\n    alias: ls-b\n    vars:\n      path: c\n",
+		},
+		{
+			name:    "hooks by name without alias",
+			base:    "preHooks:\n  - name: ls\n    alias: ls-a\n  - name: git\n",
+			overlay: "preHooks:\n  - name: git\n    vars:\n      url: u\n  - name: ls\n",
+			want:    "preHooks:\n  - name: ls\n    alias: ls-a\n  - name: git\n    vars:\n      url: u\n  - name: ls\n",
+		},
+		{
+			name:    "list without keys replaced",
+			base:    "namespaces:\n  - a\n  - b\n",
+			overlay: "namespaces:\n  - c\n",
+			want:    "namespaces:\n  - c\n",
+		},
+		{
+			name:    "list partly keyed replaced",
+			base:    "items:\n  - name: a\n  - path: b\n",
+			overlay: "items:\n  - name: a\n    path: c\n",
+			want:    "items:\n  - name: a\n    path: c\n",
+		},
+		{
+			name:    "other kind replaced",
+			base:    "a:\n  b: 1\n",
+			overlay: "a: 2\n",
+			want:    "a: 2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var base, overlay yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.base), &base); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.overlay), &overlay); err != nil {
+				t.Fatal(err)
+			}
+			merged := mergeNode(base.Content[0], overlay.Content[0])
+
+			var out strings.Builder
+			encoder := yaml.NewEncoder(&out)
+			encoder.SetIndent(2)
+			if err := encoder.Encode(merged); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("merged = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOverlays(t *testing.T) {
+	base := "kind: Project\nspec:\n  name: p\n  product: a\n  description: a\n---\n" +
+		"kind: Project\nspec:\n  name: p\n  product: b\n  description: b\n"
+	tests := []struct {
+		name    string
+		overlay string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "product of the overlay",
+			overlay: "kind: Project\nspec:\n  name: p\n  product: b\n  description: c\n",
+			want:    []string{"a", "c"},
+		},
+		{
+			name:    "several resources",
+			overlay: "kind: Project\nspec:\n  name: p\n  description: c\n",
+			wantErr: "overlay.yaml:1 (document 1): 2 resources of kind Project are named 'p', set the product of the overlay",
+		},
+		{
+			name:    "no resource",
+			overlay: "kind: Project\nspec:\n  name: q\n  product: a\n",
+			wantErr: "overlay.yaml:1 (document 1): there is no Project named 'q' to overlay",
+		},
+		{
+			name:    "no name",
+			overlay: "kind: Project\nspec:\n  product: a\n",
+			wantErr: "overlay.yaml:1 (document 1): the overlay has no spec.name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, err := newTemplateVars(&FileOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			documents, err := decodeDocuments("base.yaml", []byte(base), vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			overlays, err := decodeDocuments("overlay.yaml", []byte(tt.overlay), vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = applyOverlays(documents, overlays)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, document := range documents {
+				got = append(got, specValue(document, "description"))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("descriptions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
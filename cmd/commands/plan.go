@@ -0,0 +1,84 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/types"
+	"io"
+	"reflect"
+)
+
+// planStep is a single request of an apply or remove run.
+type planStep struct {
+	Order    int
+	Resource string
+	Method   string
+	URL      string
+	Body     []byte
+}
+
+// buildPlan builds the requests that would be sent for the resources, tier by tier.
+func buildPlan(apiServer string, skipCheck bool, method string, tiers [][]*resourceNode) ([]planStep, error) {
+	var plan []planStep
+	for _, tier := range tiers {
+		for _, node := range tier {
+			resourceObj := reflect.New(node.resourceType).Interface().(types.ResourceHandler)
+			if err := decodeResource(node.document.Node, resourceObj); err != nil {
+				return nil, fmt.Errorf("%s: %w", node.document.position(), err)
+			}
+			requestURL, requestBody, err := buildRequestURLAndBodys(apiServer, resourceObj)
+			if err != nil {
+				return nil, err
+			}
+			step := planStep{
+				Order:    len(plan) + 1,
+				Resource: describeResource(resourceObj),
+				Method:   method,
+				URL:      withSkipCheck(requestURL, skipCheck),
+			}
+			// The body of a delete request is not sent.
+			if method != MethodDelete {
+				step.Body = requestBody
+			}
+			plan = append(plan, step)
+		}
+	}
+	return plan, nil
+}
+
+// printPlan prints the execution plan, the request bodies are only printed when withBody is set.
+func printPlan(out io.Writer, plan []planStep, withBody bool) {
+	if withBody {
+		fmt.Fprintf(out, "Execution plan, %d requests (dry run, nothing is sent):\n\n", len(plan))
+	} else {
+		fmt.Fprintf(out, "Execution plan, %d requests:\n\n", len(plan))
+	}
+	for _, step := range plan {
+		fmt.Fprintf(out, "%d. %s\n", step.Order, step.Resource)
+		fmt.Fprintf(out, "   %s %s\n", step.Method, step.URL)
+		if withBody && step.Body != nil {
+			var body bytes.Buffer
+			if err := json.Indent(&body, step.Body, "   ", "  "); err != nil {
+				body.Reset()
+				body.Write(step.Body)
+			}
+			fmt.Fprintf(out, "   %s\n", body.String())
+		}
+	}
+	fmt.Fprintln(out)
+}
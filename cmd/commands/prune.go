@@ -0,0 +1,119 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/types"
+	"io"
+	"reflect"
+)
+
+// findPruneCandidates lists every product-scoped kind of the product on the API server,
+// and returns the resources that are not declared in the file, in the order of the given types.
+func findPruneCandidates(clientOptions *types.ClientOptions, product string, resourcesMap map[string][]*resourceDocument,
+	resourceTypeArr []types.ResourcesType) ([]types.ResourceHandler, error) {
+	var candidates []types.ResourceHandler
+	for _, value := range resourceTypeArr {
+		kind := value.ResourceType.Name()
+		if !isProductScoped(kind) {
+			continue
+		}
+
+		declared, err := declaredResourceNames(resourcesMap[kind], value.ResourceType, product)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := listResources(clientOptions, newResourceHandler(value.ResourceType, product, ""), value.ResponseItemType, io.Discard)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			name := reflect.Indirect(item).FieldByName("Name").String()
+			if _, ok := declared[name]; !ok {
+				candidates = append(candidates, newResourceHandler(value.ResourceType, product, name))
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// declaredResourceNames returns the names of the resources of the file which belong to the product.
+func declaredResourceNames(documents []*resourceDocument, resourceType reflect.Type, product string) (map[string]struct{}, error) {
+	names := make(map[string]struct{})
+	for _, document := range documents {
+		resourceObj := reflect.New(resourceType).Interface().(types.ResourceHandler)
+		if err := decodeResource(document.Node, resourceObj); err != nil {
+			return nil, fmt.Errorf("%s: %w", document.position(), err)
+		}
+		if getResourceProduct(resourceObj) == product {
+			names[getSpecField(resourceObj, "Name")] = struct{}{}
+		}
+	}
+	return names, nil
+}
+
+// prune deletes the resources of the product that are on the API server but not declared in the file,
+// and adds the result of every deleted resource to the report. With a client dry run the resources are only printed.
+func prune(clientOptions *types.ClientOptions, executeOptions *ExecuteOptions, resourcesMap map[string][]*resourceDocument, report *runReport) error {
+	out := executeOptions.out()
+	product := executeOptions.PruneProduct
+	if product == "" {
+		return errProductNotSet
+	}
+
+	candidates, err := findPruneCandidates(clientOptions, product, resourcesMap, executeOptions.PruneResourceTypes)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		fmt.Fprintf(out, "Nothing to prune in product '%s'.\n", product)
+		return nil
+	}
+
+	fmt.Fprintf(out, "%d resources of product '%s' are not declared in the file:\n", len(candidates), product)
+	for _, candidate := range candidates {
+		fmt.Fprintf(out, "  - %s '%s'\n", candidate.GetKind(), getSpecField(candidate, "Name"))
+	}
+	fmt.Fprintln(out)
+
+	if executeOptions.DryRun == DryRunClient {
+		fmt.Fprintln(out, "The resources are not pruned (dry run).")
+		return nil
+	}
+	if executeOptions.ConfirmPrune {
+		answer := AskToProceedS(fmt.Sprintf("Are you sure you want to remove these %d resources? [y/n] ", len(candidates)))
+		if answer == "n" {
+			fmt.Fprintln(out, "The prune was canceled.")
+			return nil
+		}
+	}
+
+	apiServer := formatAPIServer(clientOptions.ServerAddr)
+	for _, candidate := range candidates {
+		if _, err = buildResourceAndDo(MethodDelete, apiServer, clientOptions.Token, clientOptions.SkipCheck, candidate, out); err != nil {
+			fmt.Fprintf(out, "%s failed: %s\n", describeResource(candidate), err)
+			report.add(candidate.GetKind(), candidate, ResultFailed, err.Error())
+			if !executeOptions.ContinueOnError {
+				return nil
+			}
+			continue
+		}
+		fmt.Fprintf(out, "%s pruned.\n", describeResource(candidate))
+		report.add(candidate.GetKind(), candidate, ResultSucceeded, "pruned")
+	}
+	return nil
+}
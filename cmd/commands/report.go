@@ -0,0 +1,96 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/printers"
+	"github.com/nautes-labs/cli/cmd/types"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const (
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+	ResultSkipped   = "skipped"
+)
+
+// ResourceResult is the result of a single resource of an apply or remove run.
+type ResourceResult struct {
+	Kind    string `yaml:"kind" json:"kind" column:"kind"`
+	Name    string `yaml:"name" json:"name" column:"name"`
+	Product string `yaml:"product" json:"product" column:"product"`
+	Status  string `yaml:"status" json:"status" column:"status"`
+	// Message is the error of a failed resource or the reason a resource was skipped.
+	Message string `yaml:"message" json:"message" column:"message"`
+}
+
+// runReport collects the results of the resources of a run in the order they were sent.
+type runReport struct {
+	Results []*ResourceResult `yaml:"results" json:"results"`
+}
+
+func (r *runReport) add(kind string, resourceHandler types.ResourceHandler, status, message string) {
+	r.Results = append(r.Results, &ResourceResult{
+		Kind:    kind,
+		Name:    getSpecField(resourceHandler, "Name"),
+		Product: getResourceProduct(resourceHandler),
+		Status:  status,
+		// The response body of the API server may span several lines.
+		Message: strings.Join(strings.Fields(message), " "),
+	})
+}
+
+func (r *runReport) failed() int {
+	var failed int
+	for _, result := range r.Results {
+		if result.Status == ResultFailed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// checkReportOutput checks the output format of the summary, before any resource is sent.
+func checkReportOutput(output string) error {
+	switch output {
+	case OutputJson, OutputYaml, OutputWide, "":
+		return nil
+	default:
+		return fmt.Errorf("unknown output format: %s", output)
+	}
+}
+
+// printReport prints the results of the run to stdout as a table, or in JSON or YAML format.
+func printReport(report *runReport, output string) error {
+	switch output {
+	case OutputJson, OutputYaml:
+		return PrintResource(report, output)
+	case OutputWide, "":
+		var values []reflect.Value
+		for _, result := range report.Results {
+			values = append(values, reflect.ValueOf(result))
+		}
+		table, err := printers.GenerateTable(values, reflect.TypeOf(ResourceResult{}))
+		if err != nil {
+			return err
+		}
+		return printers.PrintTable(table, os.Stdout)
+	default:
+		return fmt.Errorf("unknown output format: %s", output)
+	}
+}
@@ -17,6 +17,7 @@ package commands
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/nautes-labs/cli/cmd/types"
 	"gopkg.in/yaml.v3"
@@ -25,6 +26,8 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -36,106 +39,451 @@ const (
 	CodeRepoBinding        = "CodeRepoBinding"
 )
 
-func Execute(apiServer string, token string, filePath string, skipCheck bool,
+const (
+	DryRunNone   = "none"
+	DryRunClient = "client"
+)
+
+// ExecuteOptions holds the options of an apply or remove run.
+type ExecuteOptions struct {
+	// FileOptions are the files declaring the resources.
+	FileOptions
+	// Method is the request method sent for every resource, MethodPost for apply and MethodDelete for remove.
+	Method string
+	// DryRun is DryRunNone or DryRunClient, a client dry run prints the execution plan without sending any request.
+	DryRun string
+	// Confirm prints the execution plan and asks for confirmation before sending the requests.
+	Confirm bool
+	// SkipUnchanged gets every resource first and skips the ones whose live spec already matches the file.
+	SkipUnchanged bool
+	// Prune deletes the resources of PruneProduct that are on the API server but not declared in the file.
+	Prune bool
+	// PruneProduct is the product whose resources are pruned.
+	PruneProduct string
+	// PruneResourceTypes are the types in the order the resources are pruned.
+	PruneResourceTypes []types.ResourcesType
+	// ConfirmPrune asks for confirmation before deleting the pruned resources.
+	ConfirmPrune bool
+	// ContinueOnError sends the remaining resources after a failure instead of skipping them.
+	ContinueOnError bool
+	// Parallelism is the number of resources of the same tier sent at the same time, 1 by default.
+	Parallelism int
+	// Output is the format of the summary printed to stdout. One of: json|yaml, or empty for a table.
+	Output string
+	// Out receives the progress of the run, stdout by default.
+	Out io.Writer
+}
+
+func (o *ExecuteOptions) out() io.Writer {
+	if o.Out == nil {
+		return os.Stdout
+	}
+	return o.Out
+}
+
+// Execute loads the resources of the file and calls the resource function for each of them in the order of their references,
+// resources that do not refer to each other are in the order of the given types.
+// A summary of the result of every resource is printed at the end of the run.
+func Execute(clientOptions *types.ClientOptions, executeOptions *ExecuteOptions,
 	resourceTypeArr []types.ResourcesType, resourceFunc types.ResourceFunc) error {
-	apiServer = formatAPIServer(apiServer)
-	fmt.Printf("API server: %s\n", apiServer)
+	if err := checkReportOutput(executeOptions.Output); err != nil {
+		return err
+	}
+	out := executeOptions.out()
+	apiServer := formatAPIServer(clientOptions.ServerAddr)
+	fmt.Fprintf(out, "API server: %s\n", apiServer)
+
+	// The confirmation is read from the standard input, which can not hold the resources at the same time.
+	if executeOptions.readsStdin() && executeOptions.DryRun != DryRunClient &&
+		(executeOptions.Confirm || executeOptions.Prune && executeOptions.ConfirmPrune) {
+		return fmt.Errorf("the resources are read from stdin, use --yes to skip the confirmation")
+	}
 
-	resourcesMap, err := loadResourcesMap(filePath)
+	resourcesMap, err := loadResourcesMap(&executeOptions.FileOptions, resourceTypeArr, out)
 	if err != nil {
 		return fmt.Errorf("failed to load resource file: %w", err)
 	}
 
-	// Send requests in the order of the given types.
-	for _, value := range resourceTypeArr {
-		typeName := value.ResourceType.Name()
-		for _, resource := range resourcesMap[typeName] {
-			resourceObj := reflect.New(value.ResourceType).Interface().(types.ResourceHandler)
-			if err = resourceFunc(apiServer, token, skipCheck, resource, resourceObj); err != nil {
-				return err
-			}
+	// Resources are removed after the resources referring to them.
+	graph := buildResourceGraph(resourcesMap, resourceTypeArr)
+	tiers, err := graph.tiers(executeOptions.Method == MethodDelete)
+	if err != nil {
+		return err
+	}
+	if executeOptions.Method != MethodDelete {
+		if problems := checkResources(graph.nodes); len(problems) > 0 {
+			return fmt.Errorf("%d problems found in the resources of the file:\n  %s", len(problems), strings.Join(problems, "\n  "))
+		}
+		printMissingRefs(out, graph)
+	}
+
+	switch executeOptions.DryRun {
+	case DryRunNone, "":
+	case DryRunClient:
+		plan, err := buildPlan(apiServer, clientOptions.SkipCheck, executeOptions.Method, tiers)
+		if err != nil {
+			return err
+		}
+		printPlan(out, plan, true)
+		if executeOptions.Prune {
+			return prune(clientOptions, executeOptions, resourcesMap, &runReport{})
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid dry-run value: %s, must be one of: %s|%s", executeOptions.DryRun, DryRunNone, DryRunClient)
+	}
+
+	if executeOptions.Confirm {
+		plan, err := buildPlan(apiServer, clientOptions.SkipCheck, executeOptions.Method, tiers)
+		if err != nil {
+			return err
+		}
+		if len(plan) == 0 {
+			return nil
+		}
+		printPlan(out, plan, false)
+		answer := AskToProceedS(fmt.Sprintf("Are you sure you want to send these %d requests? [y/n] ", len(plan)))
+		if answer == "n" {
+			fmt.Fprintln(out, "The command was canceled.")
+			return nil
+		}
+	}
+
+	report := sendResources(apiServer, clientOptions, executeOptions, tiers, resourceFunc)
+	if executeOptions.Prune {
+		// A resource that failed to apply may still be declared, so nothing is deleted.
+		if failed := report.failed(); failed > 0 {
+			fmt.Fprintf(os.Stderr, "prune skipped: %d resources failed\n", failed)
+		} else if err = prune(clientOptions, executeOptions, resourcesMap, report); err != nil {
+			return err
 		}
 	}
 
+	fmt.Fprintln(out)
+	if err = printReport(report, executeOptions.Output); err != nil {
+		return err
+	}
+	if failed := report.failed(); failed > 0 {
+		return fmt.Errorf("%d of %d resources failed", failed, len(report.Results))
+	}
 	return nil
 }
 
-func loadResourcesMap(filePath string) (map[string][]string, error) {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+// sendResources calls the resource function for each resource, tier by tier, and reports the result of each of them.
+// The resources of a tier are sent concurrently up to the parallelism of the run, and their output is printed in the order of the tier.
+// The resources after the first failure are skipped unless the run continues on error.
+func sendResources(apiServer string, clientOptions *types.ClientOptions, executeOptions *ExecuteOptions,
+	tiers [][]*resourceNode, resourceFunc types.ResourceFunc) *runReport {
+	out := executeOptions.out()
+	report := &runReport{}
+	var stopped bool
+	for _, tier := range tiers {
+		jobs := make([]*resourceJob, 0, len(tier))
+		for _, node := range tier {
+			jobs = append(jobs, &resourceJob{resourceType: node.resourceType, resource: node.document.Node})
+		}
+
+		if stopped {
+			for _, job := range jobs {
+				job.skip("not sent because of a previous failure")
+			}
+		} else {
+			runJobs(jobs, executeOptions.Parallelism, !executeOptions.ContinueOnError, func(job *resourceJob) {
+				sendResource(apiServer, clientOptions, executeOptions, resourceFunc, job)
+			})
+		}
+
+		for _, job := range jobs {
+			_, _ = out.Write(job.out.Bytes())
+			report.add(job.resourceType.Name(), job.handler, job.status, job.message)
+			if job.status == ResultFailed && !executeOptions.ContinueOnError {
+				stopped = true
+			}
+		}
 	}
+	return report
+}
 
-	resources := strings.Split(string(content), "---")
+// resourceJob is a resource of the file to send, with the output and the result of sending it.
+type resourceJob struct {
+	resourceType reflect.Type
+	resource     *yaml.Node
+	handler      types.ResourceHandler
+	status       string
+	message      string
+	out          bytes.Buffer
+}
 
-	fmt.Printf("%d resources found\n\n", len(resources))
+// skip decodes the resource to report its name and marks it as skipped.
+func (j *resourceJob) skip(message string) {
+	j.handler = reflect.New(j.resourceType).Interface().(types.ResourceHandler)
+	_ = decodeResource(j.resource, j.handler)
+	j.status = ResultSkipped
+	j.message = message
+}
 
-	resourcesMap := make(map[string][]string)
+// sendResource sends a single resource and records the result in the job, the output is written to the job.
+func sendResource(apiServer string, clientOptions *types.ClientOptions, executeOptions *ExecuteOptions,
+	resourceFunc types.ResourceFunc, job *resourceJob) {
+	// The resource is decoded first to report its name, a decoding error is returned by the resource function.
+	job.handler = reflect.New(job.resourceType).Interface().(types.ResourceHandler)
+	_ = decodeResource(job.resource, job.handler)
 
-	for _, resource := range resources {
-		var crMetadata types.Base
-		err := yaml.Unmarshal([]byte(resource), &crMetadata)
+	if executeOptions.SkipUnchanged {
+		unchanged, err := isResourceUnchanged(apiServer, clientOptions, job.handler)
 		if err != nil {
-			return nil, fmt.Errorf("error unmarshaling YAML: %w", err)
+			fmt.Fprintf(&job.out, "%s failed: %s\n", describeResource(job.handler), err)
+			job.status, job.message = ResultFailed, err.Error()
+			return
 		}
-		resourceList := resourcesMap[crMetadata.Kind]
-		if resourceList == nil {
-			resourceList = []string{resource}
-		} else {
-			resourceList = append(resourceList, resource)
+		if unchanged {
+			fmt.Fprintf(&job.out, "%s unchanged, skipped.\n", describeResource(job.handler))
+			job.status, job.message = ResultSkipped, "unchanged"
+			return
 		}
-		resourcesMap[crMetadata.Kind] = resourceList
 	}
 
+	job.handler = reflect.New(job.resourceType).Interface().(types.ResourceHandler)
+	if err := resourceFunc(apiServer, clientOptions.Token, clientOptions.SkipCheck, job.resource, job.handler, &job.out); err != nil {
+		fmt.Fprintf(&job.out, "%s failed: %s\n", describeResource(job.handler), err)
+		job.status, job.message = ResultFailed, err.Error()
+		return
+	}
+	job.status = ResultSucceeded
+}
+
+// runJobs runs the jobs with at most parallelism of them at the same time, starting them in order.
+// With stopOnFailure the jobs that have not started yet are skipped once a job failed.
+func runJobs(jobs []*resourceJob, parallelism int, stopOnFailure bool, run func(job *resourceJob)) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	queue := make(chan *resourceJob, len(jobs))
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism && i < len(jobs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				if stopOnFailure && failed.Load() {
+					job.skip("not sent because of a previous failure")
+					continue
+				}
+				run(job)
+				if job.status == ResultFailed {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// resourceDocument is a resource document of a file with its position, which is used in error messages.
+type resourceDocument struct {
+	File string
+	// Index is the position of the document in the file, starting at 1.
+	Index int
+	// Line is the line of the file the content of the document starts at.
+	Line int
+	Kind string
+	Node *yaml.Node
+}
+
+func (d *resourceDocument) position() string {
+	return fmt.Sprintf("%s:%d (document %d)", d.File, d.Line, d.Index)
+}
+
+// loadResourcesMap reads the resource documents of the files grouped by kind, in the order of the files.
+func loadResourcesMap(fileOptions *FileOptions, resourceTypeArr []types.ResourcesType, out io.Writer) (map[string][]*resourceDocument, error) {
+	documents, err := loadDocuments(fileOptions, resourceTypeArr)
+	if err != nil {
+		return nil, err
+	}
+
+	resourcesMap := make(map[string][]*resourceDocument)
+	for _, document := range documents {
+		resourcesMap[document.Kind] = append(resourcesMap[document.Kind], document)
+	}
+	fmt.Fprintf(out, "%d resources found\n\n", len(documents))
 	return resourcesMap, nil
 }
 
-func DeleteResource(apiServer string, token string, skipCheck bool, resource string, resourceHandler types.ResourceHandler) error {
-	if err := manageResource(MethodDelete, apiServer, token, skipCheck, resource, resourceHandler); err != nil {
-		return err
+// loadDocuments reads the resource documents of the files in order, replaces their placeholders with the values of the variables
+// and merges the overlays into them. With strict every document is checked for unknown fields first.
+func loadDocuments(fileOptions *FileOptions, resourceTypeArr []types.ResourcesType) ([]*resourceDocument, error) {
+	vars, err := newTemplateVars(fileOptions)
+	if err != nil {
+		return nil, err
+	}
+	documents, err := readDocuments(fileOptions.Filenames, fileOptions, resourceTypeArr, vars)
+	if err != nil {
+		return nil, err
+	}
+	overlays, err := readDocuments(fileOptions.Overlays, fileOptions, resourceTypeArr, vars)
+	if err != nil {
+		return nil, err
+	}
+	if err = vars.err(); err != nil {
+		return nil, err
+	}
+	if err = applyOverlays(documents, overlays); err != nil {
+		return nil, err
+	}
+	if fileOptions.ResolveKubeconfig {
+		if err = resolveKubeconfigs(documents); err != nil {
+			return nil, err
+		}
+	} else if err = checkKubeconfigSources(documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// readDocuments reads the documents of the files, directories and glob patterns in order.
+func readDocuments(filenames []string, fileOptions *FileOptions, resourceTypeArr []types.ResourcesType, vars *templateVars) ([]*resourceDocument, error) {
+	files, err := expandFilenames(filenames, fileOptions.Recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []*resourceDocument
+	for _, file := range files {
+		name, content, err := readResourceFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if fileOptions.Strict {
+			if err = checkKnownFields(name, content, resourceTypeArr); err != nil {
+				return nil, err
+			}
+		}
+		fileDocuments, err := decodeDocuments(name, content, vars)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, fileDocuments...)
+	}
+	return documents, nil
+}
+
+// decodeDocuments decodes the documents of a file and replaces their placeholders.
+// Empty and comment-only documents are skipped, a document without a kind is an error.
+func decodeDocuments(name string, content []byte, vars *templateVars) ([]*resourceDocument, error) {
+	var documents []*resourceDocument
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for index := 1; ; index++ {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("%s: document %d: error unmarshaling YAML: %w", name, index, err)
+		}
+		if isEmptyDocument(&node) {
+			continue
+		}
+		vars.substitute(&node, name)
+
+		document := &resourceDocument{File: name, Index: index, Line: node.Content[0].Line, Node: &node}
+		var crMetadata types.Base
+		if err := node.Decode(&crMetadata); err != nil {
+			return nil, fmt.Errorf("%s: error unmarshaling YAML: %w", document.position(), err)
+		}
+		if crMetadata.Kind == "" {
+			return nil, fmt.Errorf("%s: the document has no kind", document.position())
+		}
+		document.Kind = crMetadata.Kind
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+// isEmptyDocument reports whether the document has no content, or only comments.
+func isEmptyDocument(node *yaml.Node) bool {
+	if len(node.Content) == 0 {
+		return true
+	}
+	content := node.Content[0]
+	return content.Kind == yaml.ScalarNode && content.Tag == "!!null"
+}
+
+// decodeResource decodes a resource document of the file into the resource handler.
+func decodeResource(resource *yaml.Node, resourceHandler types.ResourceHandler) error {
+	if err := resource.Decode(resourceHandler); err != nil {
+		return fmt.Errorf("error unmarshaling YAML: %w", err)
 	}
-	fmt.Printf("%s deleted successfully.\n", resourceHandler.GetKind())
 	return nil
 }
 
-func SaveResource(apiServer string, token string, skipCheck bool, resource string, resourceHandler types.ResourceHandler) error {
-	if err := manageResource(MethodPost, apiServer, token, skipCheck, resource, resourceHandler); err != nil {
+func DeleteResource(apiServer string, token string, skipCheck bool, resource *yaml.Node, resourceHandler types.ResourceHandler, out io.Writer) error {
+	if err := decodeResource(resource, resourceHandler); err != nil {
 		return err
 	}
-	fmt.Printf("%s saved successfully.\n", resourceHandler.GetKind())
+	if _, err := buildResourceAndDo(MethodDelete, apiServer, token, skipCheck, resourceHandler, out); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s deleted successfully.\n", resourceHandler.GetKind())
 	return nil
 }
 
-func manageResource(method string, apiServer string, token string, skipCheck bool, resource string, resourceHandler types.ResourceHandler) error {
-	err := yaml.Unmarshal([]byte(resource), resourceHandler)
-	if err != nil {
-		fmt.Println("error unmarshaling YAML: %w", err)
+func SaveResource(apiServer string, token string, skipCheck bool, resource *yaml.Node, resourceHandler types.ResourceHandler, out io.Writer) error {
+	if err := decodeResource(resource, resourceHandler); err != nil {
 		return err
 	}
-	_, err = buildResourceAndDo(method, apiServer, token, skipCheck, resourceHandler)
-	if err != nil {
+	if _, err := buildResourceAndDo(MethodPost, apiServer, token, skipCheck, resourceHandler, out); err != nil {
 		return err
 	}
-
+	fmt.Fprintf(out, "%s saved successfully.\n", resourceHandler.GetKind())
 	return nil
 }
 
-func buildResourceAndDo(method string, apiServer string, token string, skipCheck bool, resourceHandler types.ResourceHandler) ([]byte, error) {
+// ResponseError is returned when the API server responds with a status code other than 200.
+type ResponseError struct {
+	Kind       string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("failed to operate %s:\n%s", e.Kind, e.Body)
+}
+
+// IsNotFound reports whether the error is a response error of a resource that does not exist.
+func IsNotFound(err error) bool {
+	var responseErr *ResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound
+}
+
+func buildResourceAndDo(method string, apiServer string, token string, skipCheck bool, resourceHandler types.ResourceHandler, out io.Writer) ([]byte, error) {
 	requestURL, requestBody, err := buildRequestURLAndBodys(apiServer, resourceHandler)
 	if err != nil {
 		return nil, err
 	}
-	if skipCheck {
-		requestURL = fmt.Sprintf("%s?insecure_skip_check=%t", requestURL, skipCheck)
-	}
+	requestURL = withSkipCheck(requestURL, skipCheck)
 	var resBytes []byte
 	// An exception occurred in the delete request, continue execution.
-	if resBytes, err = buildAndSendRequest(resourceHandler.GetKind(), method, requestURL, requestBody, token); err != nil {
+	if resBytes, err = buildAndSendRequest(resourceHandler.GetKind(), method, requestURL, requestBody, token, out); err != nil {
 		return nil, err
 	}
 	return resBytes, nil
 }
 
+// withSkipCheck adds the query parameter asking the API server to skip the compliance check.
+func withSkipCheck(requestURL string, skipCheck bool) string {
+	if skipCheck {
+		return fmt.Sprintf("%s?insecure_skip_check=%t", requestURL, skipCheck)
+	}
+	return requestURL
+}
+
 func formatAPIServer(apiServer string) string {
 	if strings.HasSuffix(apiServer, "/") {
 		length := len(apiServer)
@@ -196,14 +544,16 @@ func getPathVarValues(specObj interface{}, pathVarNames []string) ([]string, err
 	return pathVarValues, nil
 }
 
-func buildAndSendRequest(kind string, method string, requestURL string, requestBody []byte, token string) ([]byte, error) {
+// buildAndSendRequest sends the request to the API server and returns the response body,
+// the request is logged to out.
+func buildAndSendRequest(kind string, method string, requestURL string, requestBody []byte, token string, out io.Writer) ([]byte, error) {
 	var req *http.Request
 	var err error
 
-	fmt.Printf("Request[%s] URL: %s\n", method, requestURL)
+	fmt.Fprintf(out, "Request[%s] URL: %s\n", method, requestURL)
 
 	if requestBody != nil && method != MethodDelete {
-		fmt.Printf("Request body: %s\n\n", string(requestBody))
+		fmt.Fprintf(out, "Request body: %s\n\n", string(requestBody))
 		req, err = http.NewRequest(method, requestURL, bytes.NewBuffer(requestBody))
 	} else {
 		req, err = http.NewRequest(method, requestURL, http.NoBody)
@@ -232,6 +582,58 @@ func buildAndSendRequest(kind string, method string, requestURL string, requestB
 	if resp.StatusCode == http.StatusOK {
 		return bodyBytes, nil
 	}
-	//return nil, fmt.Errorf("failed to operate %s: status code: %d", kind, resp.StatusCode)
-	return nil, fmt.Errorf("failed to operate %s:\n%s", kind, bodyBytes)
+	return nil, &ResponseError{Kind: kind, StatusCode: resp.StatusCode, Body: bodyBytes}
+}
+
+// getSpec returns the spec of the resource handler.
+func getSpec(resourceHandler types.ResourceHandler) interface{} {
+	return reflect.ValueOf(resourceHandler).Elem().FieldByName("Spec").Interface()
+}
+
+// getSpecField returns the string field of the spec, or an empty string if the spec has no such field.
+func getSpecField(resourceHandler types.ResourceHandler, fieldName string) string {
+	field := reflect.ValueOf(resourceHandler).Elem().FieldByName("Spec").FieldByName(fieldName)
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
+}
+
+// isProductScoped reports whether resources of the kind belong to a product.
+func isProductScoped(kind string) bool {
+	return kind != IgnoreProductOfCluster && kind != IgnoreProductOfProduct
+}
+
+// newResourceHandler creates a resource handler of the given type with its kind, product and name set.
+func newResourceHandler(resourceType reflect.Type, product, name string) types.ResourceHandler {
+	resourceHandler := reflect.New(resourceType).Interface().(types.ResourceHandler)
+	resourceValue := reflect.ValueOf(resourceHandler).Elem()
+	resourceValue.FieldByName(types.ResourceKind).SetString(resourceType.Name())
+	resourceValue.FieldByName("Spec").FieldByName("Name").SetString(name)
+	setResourceProduct(resourceHandler, product)
+	return resourceHandler
+}
+
+// setResourceProduct sets the product of a product-scoped resource, which is a path parameter of its requests.
+func setResourceProduct(resourceHandler types.ResourceHandler, product string) {
+	specValue := reflect.ValueOf(resourceHandler).Elem().FieldByName("Spec")
+	switch resourceHandler.GetKind() {
+	case IgnoreProductOfCluster, IgnoreProductOfProduct:
+	case CodeRepoBinding:
+		specValue.FieldByName("ProductName").SetString(product)
+	default:
+		specValue.FieldByName("Product").SetString(product)
+	}
+}
+
+// getResourceProduct returns the product the resource belongs to, Cluster and Product have none.
+func getResourceProduct(resourceHandler types.ResourceHandler) string {
+	switch resourceHandler.GetKind() {
+	case IgnoreProductOfCluster, IgnoreProductOfProduct:
+		return ""
+	case CodeRepoBinding:
+		return getSpecField(resourceHandler, "ProductName")
+	default:
+		return getSpecField(resourceHandler, "Product")
+	}
 }
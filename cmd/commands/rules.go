@@ -0,0 +1,269 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/types"
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	IsolationShared    = "shared"
+	IsolationExclusive = "exclusive"
+)
+
+// componentPortAdditions are the additions of the known components whose value is a port, keyed by the name of the component.
+var componentPortAdditions = map[string][]string{
+	"traefik": {"httpNodePort", "httpsNodePort"},
+}
+
+// gitlabEvents are the events of a code repo a gitlab event source can receive.
+var gitlabEvents = map[string]bool{
+	"push_events":                true,
+	"tag_push_events":            true,
+	"issues_events":              true,
+	"confidential_issues_events": true,
+	"merge_requests_events":      true,
+	"note_events":                true,
+	"confidential_note_events":   true,
+	"job_events":                 true,
+	"pipeline_events":            true,
+	"wiki_page_events":           true,
+	"deployment_events":          true,
+	"releases_events":            true,
+}
+
+// checkResources checks the rules of the specs of the decoded resources,
+// and returns every problem found with the position of its document and the resource.
+func checkResources(nodes []*resourceNode) []string {
+	var problems []string
+	for _, node := range nodes {
+		if node.err != nil {
+			continue
+		}
+		for _, problem := range checkResource(node.handler) {
+			problems = append(problems, fmt.Sprintf("%s: %s: %s", node.document.position(), describeResource(node.handler), problem))
+		}
+	}
+	return problems
+}
+
+// checkResource returns the problems of the spec of the resource, kinds without rules have none.
+func checkResource(resourceHandler types.ResourceHandler) []string {
+	switch resource := resourceHandler.(type) {
+	case *types.Cluster:
+		return checkCluster(&resource.Spec)
+	case *types.ProjectPipelineRuntime:
+		return checkProjectPipelineRuntime(&resource.Spec)
+	default:
+		return nil
+	}
+}
+
+// checkCluster returns the problems of the combination of the usage, the type and the worker type of the cluster,
+// of the fields a virtual cluster requires, of the primary domain and of the ports of the components.
+func checkCluster(spec *types.ClusterResponseItem) []string {
+	var problems []string
+	switch spec.Usage {
+	case ClusterUsageHost:
+		if spec.WorkerType != "" {
+			problems = append(problems, "a host cluster must not set workerType")
+		}
+	case ClusterUsageWorker:
+		if spec.WorkerType != WorkerTypePipeline && spec.WorkerType != WorkerTypeDeployment {
+			problems = append(problems, fmt.Sprintf("the workerType of a worker cluster must be %s or %s", WorkerTypePipeline, WorkerTypeDeployment))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("usage must be %s or %s", ClusterUsageHost, ClusterUsageWorker))
+	}
+
+	switch spec.ClusterType {
+	case ClusterTypeVirtual:
+		if spec.HostCluster == "" {
+			problems = append(problems, "a virtual cluster must set hostCluster")
+		}
+		if spec.VCluster.HTTPSNodePort == "" {
+			problems = append(problems, "a virtual cluster must set vcluster.httpsNodePort")
+		}
+	case ClusterTypePhysical:
+		if spec.HostCluster != "" {
+			problems = append(problems, "only a virtual cluster can set hostCluster")
+		}
+		if spec.VCluster.HTTPSNodePort != "" {
+			problems = append(problems, "only a virtual cluster can set vcluster.httpsNodePort")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("clusterType must be %s or %s", ClusterTypePhysical, ClusterTypeVirtual))
+	}
+
+	if spec.VCluster.HTTPSNodePort != "" && !isPort(spec.VCluster.HTTPSNodePort) {
+		problems = append(problems, fmt.Sprintf("vcluster.httpsNodePort %q is not a port", spec.VCluster.HTTPSNodePort))
+	}
+	if spec.PrimaryDomain != "" {
+		if len(validation.IsDNS1123Subdomain(spec.PrimaryDomain)) > 0 {
+			problems = append(problems, fmt.Sprintf("primaryDomain %q is not a valid DNS name, it must consist of lower case alphanumeric characters, '-' or '.'", spec.PrimaryDomain))
+		}
+	}
+	return append(problems, checkComponentPorts(&spec.ComponentsList)...)
+}
+
+// checkComponentPorts returns the problems of the additions of the known components whose value must be a port.
+func checkComponentPorts(componentsList *types.ComponentsList) []string {
+	components := map[string]*types.Component{
+		"deployment":          componentsList.Deployment,
+		"eventListener":       componentsList.EventListener,
+		"gateway":             componentsList.Gateway,
+		"multiTenant":         componentsList.MultiTenant,
+		"pipeline":            componentsList.Pipeline,
+		"progressiveDelivery": componentsList.ProgressiveDelivery,
+		"secretSync":          componentsList.SecretSync,
+	}
+	componentTypes := make([]string, 0, len(components))
+	for componentType := range components {
+		componentTypes = append(componentTypes, componentType)
+	}
+	sort.Strings(componentTypes)
+
+	var problems []string
+	for _, componentType := range componentTypes {
+		component := components[componentType]
+		if component == nil {
+			continue
+		}
+		for _, addition := range componentPortAdditions[component.Name] {
+			value, ok := component.Additions[addition]
+			if ok && !isPort(value) {
+				problems = append(problems, fmt.Sprintf("componentsList.%s.additions.%s %q of %s is not a port", componentType, addition, value, component.Name))
+			}
+		}
+	}
+	return problems
+}
+
+// isPort reports whether the value is a port number between 1 and 65535.
+func isPort(value string) bool {
+	port, err := strconv.Atoi(value)
+	return err == nil && len(validation.IsValidPortNum(port)) == 0
+}
+
+// checkProjectPipelineRuntime returns the problems of the links between the triggers, the event sources and the pipelines
+// of the runtime, of its event sources, of the aliases of its hooks and of its isolation.
+func checkProjectPipelineRuntime(spec *types.ProjectPipelineRuntimeResponseItem) []string {
+	var problems []string
+	switch spec.Isolation {
+	case "", IsolationShared, IsolationExclusive:
+	default:
+		problems = append(problems, fmt.Sprintf("isolation %q must be %s or %s", spec.Isolation, IsolationShared, IsolationExclusive))
+	}
+
+	pipelines := make(map[string]bool)
+	if spec.Pipelines != nil {
+		for _, pipeline := range *spec.Pipelines {
+			pipelines[pipeline.Name] = true
+		}
+	}
+	eventSources := make(map[string]bool)
+	if spec.EventSources != nil {
+		for i, eventSource := range *spec.EventSources {
+			eventSources[eventSource.Name] = true
+			path := fmt.Sprintf("eventSources[%d]", i)
+			problems = append(problems, checkEventSource(path, &eventSource)...)
+		}
+	}
+	if spec.PipelineTriggers != nil {
+		for i, trigger := range *spec.PipelineTriggers {
+			if !eventSources[trigger.EventSource] {
+				problems = append(problems, fmt.Sprintf("pipelineTriggers[%d].eventSource %q is not an entry of eventSources", i, trigger.EventSource))
+			}
+			if !pipelines[trigger.Pipeline] {
+				problems = append(problems, fmt.Sprintf("pipelineTriggers[%d].pipeline %q is not an entry of pipelines", i, trigger.Pipeline))
+			}
+		}
+	}
+	return append(problems, checkHookAliases(spec.Hooks)...)
+}
+
+// checkEventSource returns the problems of the events of a gitlab event source and of the schedule of a calendar event source.
+func checkEventSource(path string, eventSource *types.ProjectPipelineRuntimeResponseItemEventSources) []string {
+	var problems []string
+	if eventSource.Gitlab != nil {
+		for j, event := range eventSource.Gitlab.Events {
+			if !gitlabEvents[event] {
+				problems = append(problems, fmt.Sprintf("%s.gitlab.events[%d] %q is not a supported gitlab event", path, j, event))
+			}
+		}
+	}
+
+	calendar := eventSource.Calendar
+	if calendar == nil {
+		return problems
+	}
+	switch {
+	case calendar.Schedule != "" && calendar.Interval != "":
+		problems = append(problems, fmt.Sprintf("%s.calendar must set only one of schedule and interval", path))
+	case calendar.Schedule != "":
+		if _, err := cron.ParseStandard(calendar.Schedule); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.calendar.schedule %q is not a valid cron schedule: %s", path, calendar.Schedule, err))
+		}
+	case calendar.Interval != "":
+		if interval, err := time.ParseDuration(calendar.Interval); err != nil || interval <= 0 {
+			problems = append(problems, fmt.Sprintf("%s.calendar.interval %q is not a valid duration, such as 30m or 1h", path, calendar.Interval))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%s.calendar must set a schedule or an interval", path))
+	}
+	for j, exclusionDate := range calendar.ExclusionDates {
+		if _, err := parseExclusionDate(exclusionDate, time.UTC); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.calendar.exclusionDates[%d] %q must be a date such as 2023-10-01 or an RFC 3339 time", path, j, exclusionDate))
+		}
+	}
+	if calendar.Timezone == "" {
+		problems = append(problems, fmt.Sprintf("%s.calendar must set a timezone, such as Asia/Shanghai", path))
+	} else if _, err := time.LoadLocation(calendar.Timezone); err != nil {
+		problems = append(problems, fmt.Sprintf("%s.calendar.timezone %q is not an IANA timezone", path, calendar.Timezone))
+	}
+	return problems
+}
+
+// checkHookAliases returns a problem for every hook whose alias, or name without an alias, is used by another hook of preHooks or postHooks.
+func checkHookAliases(hooks *types.Hooks) []string {
+	if hooks == nil {
+		return nil
+	}
+	var problems []string
+	seen := make(map[string]string)
+	check := func(list string, hookList []types.Hook) {
+		for i, hook := range hookList {
+			alias := hook.Name
+			if hook.Alias != nil && *hook.Alias != "" {
+				alias = *hook.Alias
+			}
+			path := fmt.Sprintf("hooks.%s[%d]", list, i)
+			if previous, ok := seen[alias]; ok {
+				problems = append(problems, fmt.Sprintf("%s has the same alias %q as %s, set a different alias", path, alias, previous))
+				continue
+			}
+			seen[alias] = path
+		}
+	}
+	check("preHooks", hooks.PreHooks)
+	check("postHooks", hooks.PostHooks)
+	return problems
+}
@@ -0,0 +1,261 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"github.com/nautes-labs/cli/cmd/types"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"testing"
+)
+
+func TestCheckCluster(t *testing.T) {
+	tests := []struct {
+		name string
+		spec types.ClusterResponseItem
+		want []string
+	}{
+		{
+			name: "physical host",
+			spec: types.ClusterResponseItem{Usage: "host", ClusterType: "physical", PrimaryDomain: "10.0.0.1.nip.io"},
+		},
+		{
+			name: "virtual host",
+			spec: types.ClusterResponseItem{Usage: "host", ClusterType: "virtual", HostCluster: "host",
+				VCluster: types.VCluster{HTTPSNodePort: "31456"}},
+		},
+		{
+			name: "host with worker type",
+			spec: types.ClusterResponseItem{Usage: "host", ClusterType: "physical", WorkerType: "pipeline"},
+			want: []string{"a host cluster must not set workerType"},
+		},
+		{
+			name: "worker without worker type",
+			spec: types.ClusterResponseItem{Usage: "worker", ClusterType: "physical"},
+			want: []string{"the workerType of a worker cluster must be pipeline or deployment"},
+		},
+		{
+			name: "virtual worker without host",
+			spec: types.ClusterResponseItem{Usage: "worker", ClusterType: "virtual", WorkerType: "deployment"},
+			want: []string{"a virtual cluster must set hostCluster", "a virtual cluster must set vcluster.httpsNodePort"},
+		},
+		{
+			name: "physical worker with virtual fields",
+			spec: types.ClusterResponseItem{Usage: "worker", ClusterType: "physical", WorkerType: "deployment", HostCluster: "host",
+				VCluster: types.VCluster{HTTPSNodePort: "70000"}},
+			want: []string{"only a virtual cluster can set hostCluster", "only a virtual cluster can set vcluster.httpsNodePort",
+				`vcluster.httpsNodePort "70000" is not a port`},
+		},
+		{
+			name: "unknown usage and cluster type",
+			spec: types.ClusterResponseItem{Usage: "edge", ClusterType: "cloud"},
+			want: []string{"usage must be host or worker", "clusterType must be physical or virtual"},
+		},
+		{
+			name: "traefik ports",
+			spec: types.ClusterResponseItem{Usage: "host", ClusterType: "physical", ComponentsList: types.ComponentsList{
+				Gateway: &types.Component{Name: "traefik", Additions: map[string]string{"httpNodePort": "30080", "httpsNodePort": "30443"}}}},
+		},
+		{
+			name: "traefik port out of range",
+			spec: types.ClusterResponseItem{Usage: "host", ClusterType: "physical", ComponentsList: types.ComponentsList{
+				Gateway: &types.Component{Name: "traefik", Additions: map[string]string{"httpNodePort": "30080", "httpsNodePort": "65536"}}}},
+			want: []string{`componentsList.gateway.additions.httpsNodePort "65536" of traefik is not a port`},
+		},
+		{
+			name: "ports of unknown components are not checked",
+			spec: types.ClusterResponseItem{Usage: "host", ClusterType: "physical", ComponentsList: types.ComponentsList{
+				Gateway: &types.Component{Name: "nginx", Additions: map[string]string{"httpNodePort": "none"}}}},
+		},
+		{
+			name: "invalid primary domain",
+			spec: types.ClusterResponseItem{Usage: "host", ClusterType: "physical", PrimaryDomain: "Example.com"},
+			want: []string{`primaryDomain "Example.com" is not a valid DNS name, it must consist of lower case alphanumeric characters, '-' or '.'`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkCluster(&tt.spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problems = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckProjectPipelineRuntime(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want []string
+	}{
+		{
+			name: "linked triggers",
+			spec: `
+isolation: exclusive
+pipelines:
+  - name: dev
+eventSources:
+  - name: webhook
+    gitlab:
+      repoName: repo
+      events: [push_events, tag_push_events]
+pipelineTriggers:
+  - eventSource: webhook
+    pipeline: dev
+`,
+		},
+		{
+			name: "trigger links missing",
+			spec: `
+pipelines:
+  - name: dev
+eventSources:
+  - name: webhook
+pipelineTriggers:
+  - eventSource: hook
+    pipeline: test
+`,
+			want: []string{`pipelineTriggers[0].eventSource "hook" is not an entry of eventSources`,
+				`pipelineTriggers[0].pipeline "test" is not an entry of pipelines`},
+		},
+		{
+			name: "unknown isolation",
+			spec: "isolation: private\n",
+			want: []string{`isolation "private" must be shared or exclusive`},
+		},
+		{
+			name: "unknown gitlab event",
+			spec: `
+eventSources:
+  - name: webhook
+    gitlab:
+      events: [push_events, push]
+`,
+			want: []string{`eventSources[0].gitlab.events[1] "push" is not a supported gitlab event`},
+		},
+		{
+			name: "hooks with different aliases",
+			spec: `
+hooks:
+  preHooks:
+    - name: ls
+      alias: ls-before
+  postHooks:
+    - name: ls
+      alias: ls-after
+`,
+		},
+		{
+			name: "hook alias used twice",
+			spec: `
+hooks:
+  preHooks:
+    - name: ls
+      alias: list
+    - name: git
+      alias: list
+`,
+			want: []string{`hooks.preHooks[1] has the same alias "list" as hooks.preHooks[0], set a different alias`},
+		},
+		{
+			name: "hook name without alias used across the lists",
+			spec: `
+hooks:
+  preHooks:
+    - name: ls
+  postHooks:
+    - name: ls
+`,
+			want: []string{`hooks.postHooks[0] has the same alias "ls" as hooks.preHooks[0], set a different alias`},
+		},
+		{
+			name: "alias equal to the name of another hook",
+			spec: `
+hooks:
+  preHooks:
+    - name: git
+  postHooks:
+    - name: ls
+      alias: git
+`,
+			want: []string{`hooks.postHooks[0] has the same alias "git" as hooks.preHooks[0], set a different alias`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var spec types.ProjectPipelineRuntimeResponseItem
+			if err := yaml.Unmarshal([]byte(tt.spec), &spec); err != nil {
+				t.Fatal(err)
+			}
+			if got := checkProjectPipelineRuntime(&spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problems = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckEventSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar types.ProjectPipelineRuntimeResponseItemEventSourcesCalendar
+		want     []string
+	}{
+		{
+			name:     "schedule",
+			calendar: types.ProjectPipelineRuntimeResponseItemEventSourcesCalendar{Schedule: "0 2 * * *", Timezone: "Asia/Shanghai"},
+		},
+		{
+			name: "interval with exclusion dates",
+			calendar: types.ProjectPipelineRuntimeResponseItemEventSourcesCalendar{Interval: "30m", Timezone: "UTC",
+				ExclusionDates: []string{"2023-10-01", "2023-10-02T08:00:00+08:00"}},
+		},
+		{
+			name:     "invalid schedule",
+			calendar: types.ProjectPipelineRuntimeResponseItemEventSourcesCalendar{Schedule: "0 2 * *", Timezone: "UTC"},
+			want:     []string{`eventSources[0].calendar.schedule "0 2 * *" is not a valid cron schedule: expected exactly 5 fields, found 4: [0 2 * *]`},
+		},
+		{
+			name:     "schedule and interval",
+			calendar: types.ProjectPipelineRuntimeResponseItemEventSourcesCalendar{Schedule: "0 2 * * *", Interval: "1h", Timezone: "UTC"},
+			want:     []string{"eventSources[0].calendar must set only one of schedule and interval"},
+		},
+		{
+			name:     "negative interval",
+			calendar: types.ProjectPipelineRuntimeResponseItemEventSourcesCalendar{Interval: "-1h", Timezone: "UTC"},
+			want:     []string{`eventSources[0].calendar.interval "-1h" is not a valid duration, such as 30m or 1h`},
+		},
+		{
+			name: "invalid date and timezone",
+			calendar: types.ProjectPipelineRuntimeResponseItemEventSourcesCalendar{Interval: "1h", Timezone: "Mars/Base",
+				ExclusionDates: []string{"10/01/2023"}},
+			want: []string{`eventSources[0].calendar.exclusionDates[0] "10/01/2023" must be a date such as 2023-10-01 or an RFC 3339 time`,
+				`eventSources[0].calendar.timezone "Mars/Base" is not an IANA timezone`},
+		},
+		{
+			name:     "missing schedule and timezone",
+			calendar: types.ProjectPipelineRuntimeResponseItemEventSourcesCalendar{},
+			want: []string{"eventSources[0].calendar must set a schedule or an interval",
+				"eventSources[0].calendar must set a timezone, such as Asia/Shanghai"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventSource := &types.ProjectPipelineRuntimeResponseItemEventSources{Name: "calendar", Calendar: &tt.calendar}
+			if got := checkEventSource("eventSources[0]", eventSource); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problems = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/types"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	// exclusionDateLayout is the layout of an exclusion date without a time, the whole day is excluded.
+	exclusionDateLayout = "2006-01-02"
+	// triggerTimeLayout is the layout of the printed trigger times.
+	triggerTimeLayout = "2006-01-02 15:04:05 MST (Mon)"
+	// maxSkippedTriggers bounds the trigger times skipped by the exclusion dates, so that a schedule excluded forever ends.
+	maxSkippedTriggers = 10000
+)
+
+// scheduleOptions holds the flags of the schedule command.
+type scheduleOptions struct {
+	FileOptions
+	product string
+	count   int
+	from    string
+}
+
+// NewPipelineRuntimeCommand creates the "ppr" command and its subcommands which inspect the pipeline runtimes.
+func NewPipelineRuntimeCommand(clientOptions *types.ClientOptions, resourceTypeArr []types.ResourcesType) *cobra.Command {
+	var command = &cobra.Command{
+		Use:     "ppr",
+		Aliases: []string{"projectpipelineruntime"},
+		Short:   "Inspect project pipeline runtimes",
+		Run: func(c *cobra.Command, args []string) {
+			c.HelpFunc()(c, args)
+		},
+	}
+	command.AddCommand(newScheduleCommand(clientOptions, resourceTypeArr))
+	return command
+}
+
+func newScheduleCommand(clientOptions *types.ClientOptions, resourceTypeArr []types.ResourcesType) *cobra.Command {
+	var options scheduleOptions
+	var command = &cobra.Command{
+		Use:   "schedule name",
+		Short: "Print the next trigger times of the calendar event sources of a pipeline runtime",
+		Long: `Print the next trigger times of every calendar event source of a pipeline runtime in the timezone of the event source,
+the trigger times on the exclusion dates are left out.
+
+The pipeline runtime is read from the API server, or with --file from the local files.`,
+		Example: `nautes ppr schedule pr-demo -p demo
+
+nautes ppr schedule pr-demo -f examples/demo-pipeline.yaml --var suffix=101 -n 10`,
+		Args: cobra.ExactArgs(1),
+		// Reading the pipeline runtime from the files does not talk to the API server.
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			if len(options.Filenames) > 0 {
+				return nil
+			}
+			return ResolveClientOptions(c, clientOptions)
+		},
+		Run: func(c *cobra.Command, args []string) {
+			from := time.Now()
+			if options.from != "" {
+				var err error
+				from, err = time.Parse(time.RFC3339, options.from)
+				if err != nil {
+					CheckError(fmt.Errorf("invalid --from %s, must be an RFC 3339 time such as 2023-10-01T08:00:00+08:00", options.from))
+				}
+			}
+
+			var spec *types.ProjectPipelineRuntimeResponseItem
+			var err error
+			if len(options.Filenames) > 0 {
+				spec, err = findPipelineRuntime(&options.FileOptions, resourceTypeArr, options.product, args[0])
+			} else {
+				product := firstNonEmpty(options.product, clientOptions.Product)
+				if product == "" {
+					CheckError(errProductNotSet)
+				}
+				spec, err = getPipelineRuntime(clientOptions, product, args[0])
+			}
+			CheckError(err)
+			CheckError(printSchedule(os.Stdout, spec, from, options.count))
+		},
+	}
+	addFileFlags(command, &options.FileOptions)
+	command.Flags().StringVarP(&options.product, "product", "p", "", "Product of the pipeline runtime, defaults to $PRODUCT or the product of the context")
+	command.Flags().IntVarP(&options.count, "count", "n", 5, "Number of trigger times printed for every calendar event source")
+	command.Flags().StringVar(&options.from, "from", "", "RFC 3339 time the trigger times are computed from, defaults to now")
+	return command
+}
+
+// getPipelineRuntime reads the pipeline runtime from the API server.
+func getPipelineRuntime(clientOptions *types.ClientOptions, product, name string) (*types.ProjectPipelineRuntimeResponseItem, error) {
+	resourceHandler := newResourceHandler(reflect.TypeOf(types.ProjectPipelineRuntime{}), product, name)
+	resBytes, err := buildResourceAndDo(MethodGet, formatAPIServer(clientOptions.ServerAddr), clientOptions.Token, clientOptions.SkipCheck, resourceHandler, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	spec := &types.ProjectPipelineRuntimeResponseItem{}
+	if err = json.Unmarshal(resBytes, spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// findPipelineRuntime reads the pipeline runtime of the name from the files, and of the product if it is set.
+func findPipelineRuntime(fileOptions *FileOptions, resourceTypeArr []types.ResourcesType, product, name string) (*types.ProjectPipelineRuntimeResponseItem, error) {
+	documents, err := loadDocuments(fileOptions, resourceTypeArr)
+	if err != nil {
+		return nil, err
+	}
+	var matches []*types.ProjectPipelineRuntimeResponseItem
+	for _, document := range documents {
+		if document.Kind != "ProjectPipelineRuntime" {
+			continue
+		}
+		runtime := &types.ProjectPipelineRuntime{}
+		if err = decodeResource(document.Node, runtime); err != nil {
+			return nil, fmt.Errorf("%s: %w", document.position(), err)
+		}
+		if runtime.Spec.Name == name && (product == "" || runtime.Spec.Product == product) {
+			matches = append(matches, &runtime.Spec)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("the files declare no ProjectPipelineRuntime named '%s'", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("the files declare %d ProjectPipelineRuntime named '%s', set the product", len(matches), name)
+	}
+}
+
+// printSchedule prints the next trigger times of every calendar event source of the pipeline runtime.
+func printSchedule(out io.Writer, spec *types.ProjectPipelineRuntimeResponseItem, from time.Time, count int) error {
+	var calendars int
+	if spec.EventSources != nil {
+		for i, eventSource := range *spec.EventSources {
+			calendar := eventSource.Calendar
+			if calendar == nil {
+				continue
+			}
+			if calendars > 0 {
+				fmt.Fprintln(out)
+			}
+			calendars++
+
+			if problems := checkEventSource(fmt.Sprintf("eventSources[%d]", i), &eventSource); len(problems) > 0 {
+				fmt.Fprintf(out, "%s: %s\n", eventSource.Name, strings.Join(problems, "; "))
+				continue
+			}
+			times, err := nextTriggerTimes(calendar, from, count)
+			if err != nil {
+				return fmt.Errorf("event source %s: %w", eventSource.Name, err)
+			}
+			fmt.Fprintf(out, "%s (%s, %s):\n", eventSource.Name, describeCalendar(calendar), calendar.Timezone)
+			if len(times) == 0 {
+				fmt.Fprintln(out, "  no trigger time")
+			}
+			for _, triggerTime := range times {
+				fmt.Fprintf(out, "  %s\n", triggerTime.Format(triggerTimeLayout))
+			}
+		}
+	}
+	if calendars == 0 {
+		fmt.Fprintf(out, "ProjectPipelineRuntime '%s' has no calendar event source.\n", spec.Name)
+	}
+	return nil
+}
+
+func describeCalendar(calendar *types.ProjectPipelineRuntimeResponseItemEventSourcesCalendar) string {
+	if calendar.Schedule != "" {
+		return fmt.Sprintf("schedule %q", calendar.Schedule)
+	}
+	return fmt.Sprintf("every %s", calendar.Interval)
+}
+
+// nextTriggerTimes returns the next count trigger times of the calendar after the time, in the timezone of the calendar.
+// The trigger times on an exclusion date are skipped, an interval starts at the time.
+func nextTriggerTimes(calendar *types.ProjectPipelineRuntimeResponseItemEventSourcesCalendar, from time.Time, count int) ([]time.Time, error) {
+	location, err := time.LoadLocation(calendar.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	exclusionDates := make(map[string]bool, len(calendar.ExclusionDates))
+	for _, exclusionDate := range calendar.ExclusionDates {
+		date, err := parseExclusionDate(exclusionDate, location)
+		if err != nil {
+			return nil, err
+		}
+		exclusionDates[date.Format(exclusionDateLayout)] = true
+	}
+
+	var next func(time.Time) time.Time
+	if calendar.Schedule != "" {
+		schedule, err := cron.ParseStandard(calendar.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		next = schedule.Next
+	} else {
+		interval, err := time.ParseDuration(calendar.Interval)
+		if err != nil {
+			return nil, err
+		}
+		next = func(t time.Time) time.Time {
+			return t.Add(interval)
+		}
+	}
+
+	var times []time.Time
+	triggerTime := from.In(location)
+	for skipped := 0; len(times) < count && skipped < maxSkippedTriggers; {
+		triggerTime = next(triggerTime)
+		if triggerTime.IsZero() {
+			break
+		}
+		if exclusionDates[triggerTime.Format(exclusionDateLayout)] {
+			skipped++
+			continue
+		}
+		times = append(times, triggerTime)
+	}
+	return times, nil
+}
+
+// parseExclusionDate parses an exclusion date, a date such as 2023-10-01 or an RFC 3339 time whose date is excluded.
+func parseExclusionDate(exclusionDate string, location *time.Location) (time.Time, error) {
+	if date, err := time.ParseInLocation(exclusionDateLayout, exclusionDate, location); err == nil {
+		return date, nil
+	}
+	date, err := time.Parse(time.RFC3339, exclusionDate)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid exclusion date %s, must be a date such as 2023-10-01 or an RFC 3339 time", exclusionDate)
+	}
+	return date.In(location), nil
+}
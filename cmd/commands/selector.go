@@ -0,0 +1,201 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"k8s.io/client-go/util/jsonpath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	sortByFlagUsage        = "Sort the resources by the values of a path of the yaml output, such as .git.gitlab.path"
+	fieldSelectorFlagUsage = "Keep the resources whose fields match, such as project=foo,envType!=prod, the fields are the paths of the yaml output"
+	nameRegexFlagUsage     = "Keep the resources whose name matches the regular expression"
+)
+
+// fieldRequirement is a requirement of a field selector, the field is the path of the field in the yaml output.
+type fieldRequirement struct {
+	field    []string
+	value    string
+	notEqual bool
+}
+
+// itemSelector sorts and filters the resources listed by the get commands on the client.
+type itemSelector struct {
+	sortBy       *jsonpath.JSONPath
+	requirements []fieldRequirement
+	nameRegex    *regexp.Regexp
+}
+
+// newItemSelector parses the sort path, the field selector and the name regular expression,
+// it returns nil when none of them is set.
+func newItemSelector(sortBy, fieldSelector, nameRegex string) (*itemSelector, error) {
+	if sortBy == "" && fieldSelector == "" && nameRegex == "" {
+		return nil, nil
+	}
+	selector := &itemSelector{}
+	if sortBy != "" {
+		path := sortBy
+		if !strings.Contains(path, "{") {
+			path = fmt.Sprintf("{%s}", path)
+		}
+		selector.sortBy = jsonpath.New("sort-by").AllowMissingKeys(true)
+		if err := selector.sortBy.Parse(path); err != nil {
+			return nil, fmt.Errorf("invalid sort path %s: %w", sortBy, err)
+		}
+	}
+	if fieldSelector != "" {
+		requirements, err := parseFieldSelector(fieldSelector)
+		if err != nil {
+			return nil, err
+		}
+		selector.requirements = requirements
+	}
+	if nameRegex != "" {
+		var err error
+		if selector.nameRegex, err = regexp.Compile(nameRegex); err != nil {
+			return nil, fmt.Errorf("invalid name regular expression %s: %w", nameRegex, err)
+		}
+	}
+	return selector, nil
+}
+
+// parseFieldSelector parses the requirements separated by commas, a requirement is field=value, field==value or field!=value.
+func parseFieldSelector(fieldSelector string) ([]fieldRequirement, error) {
+	var requirements []fieldRequirement
+	for _, requirement := range strings.Split(fieldSelector, ",") {
+		var field, value string
+		var notEqual, found bool
+		if field, value, found = strings.Cut(requirement, "!="); found {
+			notEqual = true
+		} else if field, value, found = strings.Cut(requirement, "=="); !found {
+			field, value, found = strings.Cut(requirement, "=")
+		}
+		field = strings.Trim(strings.TrimSpace(field), ".")
+		if !found || field == "" {
+			return nil, fmt.Errorf("invalid field selector %s, must be field=value or field!=value", requirement)
+		}
+		requirements = append(requirements, fieldRequirement{field: strings.Split(field, "."), value: strings.TrimSpace(value), notEqual: notEqual})
+	}
+	return requirements, nil
+}
+
+// apply returns the resources matching the field selector and the name regular expression in the sort order,
+// with the products of the resources when they are listed in every product.
+func (s *itemSelector) apply(items []reflect.Value, products []string) ([]reflect.Value, []string, error) {
+	if s == nil {
+		return items, products, nil
+	}
+
+	var indexes []int
+	sortValues := make(map[int]interface{})
+	for i, item := range items {
+		if s.nameRegex != nil && !s.nameRegex.MatchString(reflect.Indirect(item).FieldByName("Name").String()) {
+			continue
+		}
+		fields, err := yamlFields(item.Interface())
+		if err != nil {
+			return nil, nil, err
+		}
+		if !s.matches(fields) {
+			continue
+		}
+		if s.sortBy != nil {
+			results, err := s.sortBy.FindResults(fields)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error finding the sort values: %w", err)
+			}
+			if len(results) > 0 && len(results[0]) > 0 && results[0][0].CanInterface() {
+				sortValues[i] = results[0][0].Interface()
+			}
+		}
+		indexes = append(indexes, i)
+	}
+	if s.sortBy != nil {
+		sort.SliceStable(indexes, func(a, b int) bool {
+			return lessValue(sortValues[indexes[a]], sortValues[indexes[b]])
+		})
+	}
+
+	selected := make([]reflect.Value, 0, len(indexes))
+	var selectedProducts []string
+	if products != nil {
+		selectedProducts = make([]string, 0, len(indexes))
+	}
+	for _, i := range indexes {
+		selected = append(selected, items[i])
+		if products != nil {
+			selectedProducts = append(selectedProducts, products[i])
+		}
+	}
+	return selected, selectedProducts, nil
+}
+
+// matches reports whether the fields meet every requirement, a list field equals a value when it holds the value.
+// A missing field has an empty value.
+func (s *itemSelector) matches(fields interface{}) bool {
+	for _, requirement := range s.requirements {
+		if fieldEquals(fieldValue(fields, requirement.field), requirement.value) == requirement.notEqual {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldValue returns the value of the field path in the maps of the fields, or nil when it is missing.
+func fieldValue(fields interface{}, path []string) interface{} {
+	for _, name := range path {
+		values, ok := fields.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		fields = values[name]
+	}
+	return fields
+}
+
+func fieldEquals(field interface{}, value string) bool {
+	switch field := field.(type) {
+	case nil:
+		return value == ""
+	case []interface{}:
+		for _, element := range field {
+			if fieldEquals(element, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return fmt.Sprint(field) == value
+	}
+}
+
+// lessValue orders numbers by their value and the other values by their text, the missing values come last.
+func lessValue(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a != nil
+	}
+	numberA, errA := strconv.ParseFloat(fmt.Sprint(a), 64)
+	numberB, errB := strconv.ParseFloat(fmt.Sprint(b), 64)
+	if errA == nil && errB == nil {
+		return numberA < numberB
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
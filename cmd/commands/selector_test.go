@@ -0,0 +1,142 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"github.com/nautes-labs/cli/cmd/types"
+	"reflect"
+	"testing"
+)
+
+func TestItemSelector(t *testing.T) {
+	environments := []*types.EnvironmentResponseItem{
+		{Name: "env-b", Cluster: "host", EnvType: "test"},
+		{Name: "env-a", Cluster: "vcluster", EnvType: "prod"},
+		{Name: "dev-c", Cluster: "host", EnvType: "dev"},
+	}
+	tests := []struct {
+		name          string
+		sortBy        string
+		fieldSelector string
+		nameRegex     string
+		products      []string
+		want          []string
+		wantProducts  []string
+	}{
+		{name: "sort by name", sortBy: ".name", want: []string{"dev-c", "env-a", "env-b"}},
+		{name: "sort by field with braces", sortBy: "{.envType}", want: []string{"dev-c", "env-a", "env-b"}},
+		{name: "equal", fieldSelector: "cluster=host", want: []string{"env-b", "dev-c"}},
+		{name: "double equal and not equal", fieldSelector: "cluster==host,envType!=dev", want: []string{"env-b"}},
+		{name: "missing field is empty", fieldSelector: "product=", want: []string{"env-b", "env-a", "dev-c"}},
+		{name: "name regex", nameRegex: "^env-", want: []string{"env-b", "env-a"}},
+		{
+			name:         "products follow the items",
+			sortBy:       ".name",
+			nameRegex:    "^env-",
+			products:     []string{"demo-1", "demo-2", "demo-3"},
+			want:         []string{"env-a", "env-b"},
+			wantProducts: []string{"demo-2", "demo-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := newItemSelector(tt.sortBy, tt.fieldSelector, tt.nameRegex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			items := make([]reflect.Value, 0, len(environments))
+			for _, environment := range environments {
+				items = append(items, reflect.ValueOf(environment))
+			}
+			selected, products, err := selector.apply(items, tt.products)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, item := range selected {
+				names = append(names, item.Elem().FieldByName("Name").String())
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("names = %v, want %v", names, tt.want)
+			}
+			if !reflect.DeepEqual(products, tt.wantProducts) {
+				t.Errorf("products = %v, want %v", products, tt.wantProducts)
+			}
+		})
+	}
+}
+
+func TestNewItemSelectorErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		sortBy        string
+		fieldSelector string
+		nameRegex     string
+	}{
+		{name: "field selector without operator", fieldSelector: "cluster"},
+		{name: "field selector without field", fieldSelector: "=host"},
+		{name: "invalid sort path", sortBy: "{.name"},
+		{name: "invalid name regex", nameRegex: "("},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newItemSelector(tt.sortBy, tt.fieldSelector, tt.nameRegex); err == nil {
+				t.Error("error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestFieldEquals(t *testing.T) {
+	tests := []struct {
+		name  string
+		field interface{}
+		value string
+		want  bool
+	}{
+		{name: "string", field: "host", value: "host", want: true},
+		{name: "number", field: 6443, value: "6443", want: true},
+		{name: "list holding the value", field: []interface{}{"a", "b"}, value: "b", want: true},
+		{name: "list without the value", field: []interface{}{"a"}, value: "b"},
+		{name: "missing", field: nil, value: "", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldEquals(tt.field, tt.value); got != tt.want {
+				t.Errorf("fieldEquals = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLessValue(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b interface{}
+		want bool
+	}{
+		{name: "numbers by value", a: 9, b: 10, want: true},
+		{name: "text", a: "b", b: "a"},
+		{name: "missing last", a: "a", b: nil, want: true},
+		{name: "missing not first", a: nil, b: "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lessValue(tt.a, tt.b); got != tt.want {
+				t.Errorf("lessValue = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/types"
+	"gopkg.in/yaml.v3"
+	"io"
+	"reflect"
+	"strings"
+)
+
+const FlagStrict = "strict"
+
+// checkKnownFields decodes every document of the file into the type of its kind with unknown fields rejected,
+// and returns an error listing every unknown field with the file, the index of its document and its line.
+// The values of the fields are not checked, they may hold placeholders which are replaced later.
+func checkKnownFields(filePath string, content []byte, resourceTypeArr []types.ResourcesType) error {
+	resourceTypes := make(map[string]reflect.Type, len(resourceTypeArr))
+	for _, value := range resourceTypeArr {
+		resourceTypes[value.ResourceType.Name()] = value.ResourceType
+	}
+
+	// Both decoders read the whole file, so the lines of the errors are lines of the file.
+	nodeDecoder := yaml.NewDecoder(bytes.NewReader(content))
+	strictDecoder := yaml.NewDecoder(bytes.NewReader(content))
+	strictDecoder.KnownFields(true)
+
+	var problems []string
+	for index := 1; ; index++ {
+		var node yaml.Node
+		if err := nodeDecoder.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%s: document %d: %w", filePath, index, err)
+		}
+
+		var crMetadata types.Base
+		_ = node.Decode(&crMetadata)
+		var target interface{} = &yaml.Node{}
+		if resourceType, ok := resourceTypes[crMetadata.Kind]; ok {
+			target = reflect.New(resourceType).Interface()
+		}
+
+		err := strictDecoder.Decode(target)
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			for _, message := range typeErr.Errors {
+				if !strings.Contains(message, " not found in type ") {
+					continue
+				}
+				problems = append(problems, fmt.Sprintf("%s: document %d: %s", filePath, index, message))
+			}
+		} else if err != nil {
+			return fmt.Errorf("%s: document %d: %w", filePath, index, err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("the file has fields that are not known, use --%s=false to ignore them:\n  %s", FlagStrict, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"testing"
+)
+
+func TestCheckKnownFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{
+			name:     "known fields",
+			manifest: "kind: Environment\nspec:\n  name: env\n  product: demo\n  cluster: $cluster\n  envType: test\n",
+		},
+		{
+			name:     "unknown kind is not checked",
+			manifest: "kind: Unknown\nspec:\n  anything: 1\n",
+		},
+		{
+			name: "unknown fields of every document",
+			manifest: "kind: Environment\nspec:\n  name: env\n  clusters: host\n---\n" +
+				"kind: Project\nspec:\n  name: project\n  lang: golang\n",
+			wantErr: "the file has fields that are not known, use --strict=false to ignore them:\n" +
+				"  test.yaml: document 1: line 4: field clusters not found in type types.EnvironmentResponseItem\n" +
+				"  test.yaml: document 2: line 9: field lang not found in type types.ProjectResponseItem",
+		},
+		{
+			name:     "invalid yaml",
+			manifest: "kind: Environment\nspec: [\n",
+			wantErr:  "test.yaml: document 1: yaml: line 2: did not find expected node content",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkKnownFields("test.yaml", []byte(tt.manifest), testResourceTypes)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
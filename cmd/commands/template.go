@@ -0,0 +1,166 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/types"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	FlagVar       = "var"
+	FlagVarFile   = "var-file"
+	FlagExpandEnv = "expand-env"
+)
+
+// Render prints the resource documents of the files with their placeholders replaced, as a multi-document YAML stream in the order of the files.
+func Render(fileOptions *FileOptions, resourceTypeArr []types.ResourcesType, out io.Writer) error {
+	documents, err := loadDocuments(fileOptions, resourceTypeArr)
+	if err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	for _, document := range documents {
+		if err = encoder.Encode(document.Node); err != nil {
+			return fmt.Errorf("%s: unable to marshal resource to yaml: %w", document.position(), err)
+		}
+	}
+	return encoder.Close()
+}
+
+// placeholderPattern matches "$$", "${name}" and "$name", a name may contain hyphens, such as "$api-server", but does not end with one.
+var placeholderPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_-]*)\}|\$([A-Za-z_][A-Za-z0-9_]*(?:-[A-Za-z0-9_]+)*)`)
+
+// templateVars replaces the placeholders of the scalars of the resource documents with the values of the variables,
+// and records the placeholders that have no value.
+type templateVars struct {
+	values    map[string]string
+	expandEnv bool
+	// unresolved are the positions of the placeholders without a value, keyed by name.
+	unresolved map[string][]string
+}
+
+// newTemplateVars reads the variable files in order, then the variables given as name=value, which take precedence.
+func newTemplateVars(fileOptions *FileOptions) (*templateVars, error) {
+	vars := &templateVars{
+		values:     make(map[string]string),
+		expandEnv:  fileOptions.ExpandEnv,
+		unresolved: make(map[string][]string),
+	}
+	for _, varFile := range fileOptions.VarFiles {
+		if err := vars.readFile(varFile); err != nil {
+			return nil, err
+		}
+	}
+	for _, variable := range fileOptions.Vars {
+		name, value, ok := strings.Cut(variable, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid variable %q, must be name=value", variable)
+		}
+		vars.values[name] = value
+	}
+	return vars, nil
+}
+
+// readFile reads a YAML file mapping variable names to scalar values.
+func (v *templateVars) readFile(varFile string) error {
+	content, err := os.ReadFile(varFile)
+	if err != nil {
+		return fmt.Errorf("error reading variable file: %w", err)
+	}
+	var values map[string]interface{}
+	if err = yaml.Unmarshal(content, &values); err != nil {
+		return fmt.Errorf("%s: error unmarshaling YAML: %w", varFile, err)
+	}
+	for name, value := range values {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("%s: the value of variable %s must be a scalar", varFile, name)
+		case nil:
+			v.values[name] = ""
+		default:
+			v.values[name] = fmt.Sprint(value)
+		}
+	}
+	return nil
+}
+
+func (v *templateVars) lookup(name string) (string, bool) {
+	if value, ok := v.values[name]; ok {
+		return value, true
+	}
+	if v.expandEnv {
+		return os.LookupEnv(name)
+	}
+	return "", false
+}
+
+// substitute replaces the placeholders of every scalar of the node, keys included, comments are left unchanged.
+// A plain scalar is resolved again after the replacement, so that a number replacing a placeholder is a number.
+func (v *templateVars) substitute(node *yaml.Node, file string) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.MappingNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			v.substitute(child, file)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "$") {
+			return
+		}
+		node.Value = placeholderPattern.ReplaceAllStringFunc(node.Value, func(placeholder string) string {
+			if placeholder == "$$" {
+				return "$"
+			}
+			match := placeholderPattern.FindStringSubmatch(placeholder)
+			name := match[1] + match[2]
+			value, ok := v.lookup(name)
+			if !ok {
+				v.unresolved[name] = append(v.unresolved[name], fmt.Sprintf("%s:%d", file, node.Line))
+				return placeholder
+			}
+			return value
+		})
+		if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			node.Tag = ""
+		}
+	}
+}
+
+// err returns an error listing every placeholder without a value with its positions.
+func (v *templateVars) err() error {
+	if len(v.unresolved) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(v.unresolved))
+	for name := range v.unresolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("$%s at %s", name, strings.Join(v.unresolved[name], ", ")))
+	}
+	return fmt.Errorf("%d placeholders have no value, set them with --%s or --%s:\n  %s",
+		len(names), FlagVar, FlagVarFile, strings.Join(lines, "\n  "))
+}
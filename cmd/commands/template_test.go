@@ -0,0 +1,152 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+)
+
+func TestSubstitute(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileOptions FileOptions
+		env         map[string]string
+		document    string
+		want        string
+		wantErr     string
+	}{
+		{
+			name:        "bare and braced",
+			fileOptions: FileOptions{Vars: []string{"suffix=1"}},
+			document:    "name: demo-$suffix\npath: ${suffix}/demo\n",
+			want:        "name: demo-1\npath: 1/demo\n",
+		},
+		{
+			name:        "names with hyphens",
+			fileOptions: FileOptions{Vars: []string{"a=1", "a-b=2"}},
+			document:    "braced: ${a-b}\nbare: $a-b\ntrailing: $a-\nbraced-prefix: ${a}-b\n",
+			want:        "braced: 2\nbare: 2\ntrailing: 1-\nbraced-prefix: 1-b\n",
+		},
+		{
+			name:        "escaped dollar",
+			fileOptions: FileOptions{Vars: []string{"a=1"}},
+			document:    "price: $$a costs $$5\n",
+			want:        "price: $a costs $5\n",
+		},
+		{
+			name:        "keys",
+			fileOptions: FileOptions{Vars: []string{"product-name=demo"}},
+			document:    "$product-name:\n  - $product-name\n",
+			want:        "demo:\n  - demo\n",
+		},
+		{
+			name:        "number",
+			fileOptions: FileOptions{Vars: []string{"port=6443"}},
+			document:    "plain: $port\nquoted: \"$port\"\n",
+			want:        "plain: 6443\nquoted: \"6443\"\n",
+		},
+		{
+			name:        "comments unchanged",
+			fileOptions: FileOptions{Vars: []string{"a=1"}},
+			document:    "# $b\nname: $a\n",
+			want:        "# $b\nname: 1\n",
+		},
+		{
+			name:        "environment",
+			fileOptions: FileOptions{Vars: []string{"a=1"}, ExpandEnv: true},
+			env:         map[string]string{"a": "2", "NAUTES_TEST_B": "3"},
+			document:    "a: $a\nb: $NAUTES_TEST_B\n",
+			want:        "a: 1\nb: 3\n",
+		},
+		{
+			name:     "escaped dollar without variables",
+			document: "password: pa$$word\n",
+			want:     "password: pa$word\n",
+		},
+		{
+			name:     "unresolved",
+			document: "name: $b\nhost: ${c-d}\nbare: $c-d-\n",
+			wantErr: "2 placeholders have no value, set them with --var or --var-file:\n" +
+				"  $b at test.yaml:1\n  $c-d at test.yaml:2, test.yaml:3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			vars, err := newTemplateVars(&tt.fileOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var node yaml.Node
+			if err = yaml.Unmarshal([]byte(tt.document), &node); err != nil {
+				t.Fatal(err)
+			}
+			vars.substitute(&node, "test.yaml")
+			if err = vars.err(); tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			encoder := yaml.NewEncoder(&out)
+			encoder.SetIndent(2)
+			if err = encoder.Encode(&node); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("document = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTemplateVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "later variable wins", vars: []string{"a=1", "a=2"}, want: map[string]string{"a": "2"}},
+		{name: "value with equal sign", vars: []string{"a=b=c"}, want: map[string]string{"a": "b=c"}},
+		{name: "empty value", vars: []string{"a="}, want: map[string]string{"a": ""}},
+		{name: "missing value", vars: []string{"a"}, wantErr: true},
+		{name: "missing name", vars: []string{"=1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, err := newTemplateVars(&FileOptions{Vars: tt.vars})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			for name, value := range tt.want {
+				if got, ok := vars.lookup(name); !ok || got != value {
+					t.Errorf("%s = %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"github.com/nautes-labs/cli/cmd/types"
+	"io"
+	"reflect"
+)
+
+// ValidateOptions holds the options of a validate run.
+type ValidateOptions struct {
+	// FileOptions are the files declaring the resources.
+	FileOptions
+	// Remote resolves the references to resources that are not declared in the file on the API server.
+	Remote bool
+	// Out receives the problems found.
+	Out io.Writer
+}
+
+// Validate checks that the resources of the file can be decoded, that their specs follow the rules of their kind,
+// that every reference resolves to a resource declared in the file, or with remote to a resource on the API server,
+// and that the references have no cycle.
+// Every problem found is printed, and the number of problems is returned.
+func Validate(clientOptions *types.ClientOptions, validateOptions *ValidateOptions, resourceTypeArr []types.ResourcesType) (int, error) {
+	out := validateOptions.Out
+	resourcesMap, err := loadResourcesMap(&validateOptions.FileOptions, resourceTypeArr, io.Discard)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load resource file: %w", err)
+	}
+
+	graph := buildResourceGraph(resourcesMap, resourceTypeArr)
+	var problems int
+	for _, node := range graph.nodes {
+		if node.err != nil {
+			fmt.Fprintf(out, "%s: %s: %s\n", node.document.position(), describeResource(node.handler), node.err)
+			problems++
+		}
+		if _, err := kubeconfigSource(node.document); err != nil {
+			fmt.Fprintf(out, "%s: %s: %s\n", node.document.position(), describeResource(node.handler), err)
+			problems++
+		}
+	}
+
+	for _, problem := range checkResources(graph.nodes) {
+		fmt.Fprintln(out, problem)
+		problems++
+	}
+
+	var resolver *remoteResolver
+	if validateOptions.Remote {
+		resolver = newRemoteResolver(clientOptions, resourceTypeArr)
+	}
+	for _, missing := range graph.missing {
+		where := "is not declared in the file"
+		if resolver != nil {
+			found, err := resolver.exists(missing.Ref)
+			if err != nil {
+				return problems, err
+			}
+			if found {
+				continue
+			}
+			where = "is not declared in the file and not found on the API server"
+		}
+		fmt.Fprintf(out, "%s: %s: %s refers to %s, which %s\n",
+			missing.node.document.position(), describeResource(missing.node.handler), missing.Path, missing.Ref, where)
+		problems++
+	}
+
+	if _, err = graph.tiers(false); err != nil {
+		fmt.Fprintln(out, err)
+		problems++
+	}
+
+	if problems == 0 {
+		fmt.Fprintf(out, "%d resources are valid.\n", len(graph.nodes))
+	} else {
+		fmt.Fprintf(out, "\n%d problems found in %d resources.\n", problems, len(graph.nodes))
+	}
+	return problems, nil
+}
+
+// remoteResolver looks up the referenced resources on the API server, every resource is requested once.
+type remoteResolver struct {
+	clientOptions *types.ClientOptions
+	apiServer     string
+	resourceTypes map[string]reflect.Type
+	found         map[resourceRef]bool
+}
+
+func newRemoteResolver(clientOptions *types.ClientOptions, resourceTypeArr []types.ResourcesType) *remoteResolver {
+	resolver := &remoteResolver{
+		clientOptions: clientOptions,
+		apiServer:     formatAPIServer(clientOptions.ServerAddr),
+		resourceTypes: make(map[string]reflect.Type, len(resourceTypeArr)),
+		found:         make(map[resourceRef]bool),
+	}
+	for _, value := range resourceTypeArr {
+		resolver.resourceTypes[value.ResourceType.Name()] = value.ResourceType
+	}
+	return resolver
+}
+
+// exists reports whether the resource is on the API server.
+func (r *remoteResolver) exists(ref resourceRef) (bool, error) {
+	if found, ok := r.found[ref]; ok {
+		return found, nil
+	}
+	resourceType, ok := r.resourceTypes[ref.Kind]
+	if !ok {
+		return false, fmt.Errorf("unknown resource kind %s", ref.Kind)
+	}
+
+	resourceHandler := newResourceHandler(resourceType, ref.Product, ref.Name)
+	_, err := buildResourceAndDo(MethodGet, r.apiServer, r.clientOptions.Token, r.clientOptions.SkipCheck, resourceHandler, io.Discard)
+	if err != nil && !IsNotFound(err) {
+		return false, err
+	}
+	r.found[ref] = err == nil
+	return err == nil, nil
+}
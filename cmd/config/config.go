@@ -0,0 +1,158 @@
+// Copyright 2023 Nautes Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// EnvConfig is the environment variable that overrides the default config file path.
+	EnvConfig = "NAUTES_CONFIG"
+	// EnvContext is the environment variable that overrides the current context of the config file.
+	EnvContext = "NAUTES_CONTEXT"
+
+	defaultConfigDir  = ".nautes"
+	defaultConfigFile = "config"
+)
+
+// Config is the content of the CLI config file, a list of named contexts and the one in use.
+type Config struct {
+	CurrentContext string     `yaml:"currentContext"`
+	Contexts       []*Context `yaml:"contexts"`
+}
+
+// Context holds the settings used to talk to one Nautes API server.
+type Context struct {
+	Name   string `yaml:"name" json:"name"`
+	Server string `yaml:"server" json:"server"`
+	// Token is the literal authentication token, prefer TokenEnv or TokenFile to keep it out of the file.
+	// +optional
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+	// TokenEnv is the name of the environment variable holding the token.
+	// +optional
+	TokenEnv string `yaml:"tokenEnv,omitempty" json:"token_env,omitempty"`
+	// TokenFile is the path of a file holding the token.
+	// +optional
+	TokenFile string `yaml:"tokenFile,omitempty" json:"token_file,omitempty"`
+	// Product is the default product of product-scoped commands.
+	// +optional
+	Product string `yaml:"product,omitempty" json:"product,omitempty"`
+	// InsecureSkipCheck is the default of the insecure flag.
+	// +optional
+	InsecureSkipCheck bool `yaml:"insecureSkipCheck,omitempty" json:"insecure_skip_check,omitempty"`
+}
+
+// DefaultPath returns the config file path, taken from NAUTES_CONFIG or ~/.nautes/config.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, defaultConfigDir, defaultConfigFile), nil
+}
+
+// Load reads the config file, a missing file results in an empty config.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+	if err = yaml.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("error unmarshaling config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes the config file, the file is only readable by the current user because it may contain tokens.
+func (c *Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("unable to marshal config to yaml: %w", err)
+	}
+	if err = os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// GetContext returns the context with the given name, or nil if it does not exist.
+func (c *Config) GetContext(name string) *Context {
+	for _, ctx := range c.Contexts {
+		if ctx.Name == name {
+			return ctx
+		}
+	}
+	return nil
+}
+
+// SetContext adds the context, or replaces the existing context with the same name.
+func (c *Config) SetContext(context *Context) {
+	for i, ctx := range c.Contexts {
+		if ctx.Name == context.Name {
+			c.Contexts[i] = context
+			return
+		}
+	}
+	c.Contexts = append(c.Contexts, context)
+}
+
+// ResolveToken returns the token of the context, looking at Token, TokenEnv and TokenFile in that order.
+func (c *Context) ResolveToken() (string, error) {
+	switch {
+	case c.Token != "":
+		return c.Token, nil
+	case c.TokenEnv != "":
+		token := os.Getenv(c.TokenEnv)
+		if token == "" {
+			return "", fmt.Errorf("context %s: environment variable %s is empty", c.Name, c.TokenEnv)
+		}
+		return token, nil
+	case c.TokenFile != "":
+		content, err := os.ReadFile(ExpandHome(c.TokenFile))
+		if err != nil {
+			return "", fmt.Errorf("context %s: error reading token file: %w", c.Name, err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+	return "", nil
+}
+
+// ExpandHome replaces a leading "~/" of the path with the home directory of the user.
+func ExpandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
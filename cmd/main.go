@@ -26,7 +26,15 @@ import (
 )
 
 func main() {
-	var filePath string
+	var fileOpts commands.FileOptions
+	var dryRun string
+	var noPrompt bool
+	var force bool
+	var prune bool
+	var product string
+	var continueOnError bool
+	var output string
+	var parallelism int
 	var clientOpts types.ClientOptions
 	var resourcesTypeArr = []types.ResourcesType{
 		{
@@ -143,8 +151,30 @@ func main() {
 		Use:   "apply",
 		Short: "Apply resources",
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := commands.Execute(clientOpts.ServerAddr, clientOpts.Token, filePath, clientOpts.SkipCheck, applyResourceTypes, commands.SaveResource); err != nil {
-				fmt.Println(err)
+			executeOpts := &commands.ExecuteOptions{
+				FileOptions:     fileOpts,
+				Method:          commands.MethodPost,
+				DryRun:          dryRun,
+				SkipUnchanged:   !force,
+				ContinueOnError: continueOnError,
+				Parallelism:     parallelism,
+				Output:          output,
+			}
+			executeOpts.ResolveKubeconfig = true
+			if output == commands.OutputJson || output == commands.OutputYaml {
+				executeOpts.Out = os.Stderr
+			}
+			if prune {
+				executeOpts.Prune = true
+				executeOpts.PruneProduct = product
+				if executeOpts.PruneProduct == "" {
+					executeOpts.PruneProduct = clientOpts.Product
+				}
+				executeOpts.PruneResourceTypes = removeResourceTypes
+				executeOpts.ConfirmPrune = !noPrompt
+			}
+			if err := commands.Execute(&clientOpts, executeOpts, applyResourceTypes, commands.SaveResource); err != nil {
+				fmt.Fprintln(os.Stderr, err)
 				os.Exit(1)
 			}
 		},
@@ -154,73 +184,203 @@ func main() {
 		Use:   "remove",
 		Short: "Remove resources",
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := commands.Execute(clientOpts.ServerAddr, clientOpts.Token, filePath, clientOpts.SkipCheck, removeResourceTypes, commands.DeleteResource); err != nil {
-				fmt.Println(err)
+			executeOpts := &commands.ExecuteOptions{
+				FileOptions:     fileOpts,
+				Method:          commands.MethodDelete,
+				DryRun:          dryRun,
+				Confirm:         !noPrompt,
+				ContinueOnError: continueOnError,
+				Parallelism:     parallelism,
+				Output:          output,
+			}
+			if output == commands.OutputJson || output == commands.OutputYaml {
+				executeOpts.Out = os.Stderr
+			}
+			if err := commands.Execute(&clientOpts, executeOpts, removeResourceTypes, commands.DeleteResource); err != nil {
+				fmt.Fprintln(os.Stderr, err)
 				os.Exit(1)
 			}
 		},
 	}
 
-	applyCmd.Flags().StringVarP(&filePath, "file", "f", "", "Path to the input file (required)")
-	applyCmd.Flags().BoolVarP(&clientOpts.SkipCheck, "insecure", "i", false, "Skipping the compliance check (optional)")
-	err := applyCmd.MarkFlagRequired("file")
-	if err != nil {
-		commands.CheckError(err)
-	}
+	commands.AddFileFlags(applyCmd, &fileOpts)
+	applyCmd.Flags().BoolVarP(&clientOpts.SkipCheck, commands.FlagInsecure, "i", false, "Skipping the compliance check (optional)")
+	applyCmd.Flags().StringVar(&dryRun, "dry-run", commands.DryRunNone, "Must be \"none\" or \"client\". If client, only print the requests that would be sent, without sending them")
+	applyCmd.Flags().Lookup("dry-run").NoOptDefVal = commands.DryRunClient
+	applyCmd.Flags().BoolVar(&continueOnError, "continue-on-error", false, "Keep sending the remaining resources after a resource failed")
+	applyCmd.Flags().IntVar(&parallelism, "parallelism", 1, "Number of resources of the same apply order sent at the same time")
+	applyCmd.Flags().StringVarP(&output, "output", "o", "", "Output format of the summary. One of: json|yaml, a table by default")
+	applyCmd.Flags().BoolVar(&force, "force", false, "Send every resource, including the ones that are unchanged on the API server")
+	applyCmd.Flags().BoolVar(&prune, "prune", false, "Remove the resources of the product that are on the API server but not declared in the file")
+	applyCmd.Flags().StringVarP(&product, "product", "p", "", "Product to prune, defaults to $PRODUCT or the product of the context")
+	applyCmd.Flags().BoolVarP(&noPrompt, "yes", "y", false, "Turn off prompting to confirm remove of pruned resources")
 	rootCmd.AddCommand(applyCmd)
 
-	removeCmd.Flags().StringVarP(&filePath, "file", "f", "", "Path to the input file (required)")
-	removeCmd.Flags().BoolVarP(&clientOpts.SkipCheck, "insecure", "i", false, "Skipping the compliance check (optional)")
-	err = removeCmd.MarkFlagRequired("file")
-	if err != nil {
-		commands.CheckError(err)
-	}
+	commands.AddFileFlags(removeCmd, &fileOpts)
+	removeCmd.Flags().BoolVarP(&clientOpts.SkipCheck, commands.FlagInsecure, "i", false, "Skipping the compliance check (optional)")
+	removeCmd.Flags().StringVar(&dryRun, "dry-run", commands.DryRunNone, "Must be \"none\" or \"client\". If client, only print the requests that would be sent, without sending them")
+	removeCmd.Flags().Lookup("dry-run").NoOptDefVal = commands.DryRunClient
+	removeCmd.Flags().BoolVar(&continueOnError, "continue-on-error", false, "Keep sending the remaining resources after a resource failed")
+	removeCmd.Flags().IntVar(&parallelism, "parallelism", 1, "Number of resources of the same remove order sent at the same time")
+	removeCmd.Flags().StringVarP(&output, "output", "o", "", "Output format of the summary. One of: json|yaml, a table by default")
+	removeCmd.Flags().BoolVarP(&noPrompt, "yes", "y", false, "Turn off prompting to confirm remove of resources")
 	rootCmd.AddCommand(removeCmd)
 
-	rootCmd.PersistentFlags().StringVarP(&clientOpts.Token, "token", "t", "", "Authentication token (required)")
-	err = rootCmd.MarkPersistentFlagRequired("token")
-	if err != nil {
-		commands.CheckError(err)
+	var diffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Diff resources against the live state of the API server",
+		Long: `Diff the resources of the file against the live state of the API server.
+
+A Cluster kubeconfig is not returned by the API server, so it can not be compared: a Cluster declaring a kubeconfig
+is always shown as changed, and apply always sends it. The kubeconfig itself is not printed.
+The kubeconfigFrom of a Cluster is not loaded, so its command is not run, and it is shown as a changed kubeconfig.
+
+The exit status is 0 when nothing would change, 1 when at least one resource would be created or changed, and 2 on error.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			diffOpts := &commands.DiffOptions{
+				FileOptions: fileOpts,
+				Out:         os.Stdout,
+			}
+			hasDiff, err := commands.Diff(&clientOpts, diffOpts, applyResourceTypes)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			if hasDiff {
+				os.Exit(1)
+			}
+		},
 	}
 
-	rootCmd.PersistentFlags().StringVarP(&clientOpts.ServerAddr, "api-server", "s", "", "URL to API server (required)")
-	err = rootCmd.MarkPersistentFlagRequired("api-server")
-	if err != nil {
-		commands.CheckError(err)
+	commands.AddFileFlags(diffCmd, &fileOpts)
+	diffCmd.Flags().BoolVarP(&clientOpts.SkipCheck, commands.FlagInsecure, "i", false, "Skipping the compliance check (optional)")
+	rootCmd.AddCommand(diffCmd)
+
+	var remote bool
+	var validateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the references between resources",
+		Long: `Validate that the resources of the file can be decoded and that every reference between them resolves,
+such as the cluster of an environment or the code repo of a deployment runtime.
+
+References to resources that are not declared in the file are problems, unless --remote finds them on the API server.
+The exit status is 0 when the file is valid and 1 otherwise.`,
+		// Without --remote nothing is sent, so the API server is not required.
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			if !remote {
+				return nil
+			}
+			return commands.ResolveClientOptions(c, &clientOpts)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			validateOpts := &commands.ValidateOptions{
+				FileOptions: fileOpts,
+				Remote:      remote,
+				Out:         os.Stdout,
+			}
+			problems, err := commands.Validate(&clientOpts, validateOpts, applyResourceTypes)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if problems > 0 {
+				os.Exit(1)
+			}
+		},
 	}
 
-	if os.Getenv("API_SERVER") != "" {
-		clientOpts.ServerAddr = os.Getenv("API_SERVER")
-		err = rootCmd.PersistentFlags().Set("api-server", clientOpts.ServerAddr)
-		if err != nil {
-			commands.CheckError(err)
-		}
+	commands.AddFileFlags(validateCmd, &fileOpts)
+	validateCmd.Flags().BoolVar(&remote, "remote", false, "Look up the referenced resources that are not declared in the file on the API server")
+	rootCmd.AddCommand(validateCmd)
+
+	var renderCmd = &cobra.Command{
+		Use:   "render",
+		Short: "Print resources with their placeholders replaced",
+		Long: `Print the resources of the files with their placeholders, such as $suffix or ${suffix}, replaced by the values of the variables.
+The kubeconfigFrom of a Cluster is printed as it is, it is only loaded by apply.
+The output is the input of apply, and nothing is sent to the API server.`,
+		// Nothing is sent, so the API server is not required.
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := commands.Render(&fileOpts, applyResourceTypes, os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
 	}
 
-	if os.Getenv("GIT_TOKEN") != "" {
-		clientOpts.Token = os.Getenv("GIT_TOKEN")
-		err = rootCmd.PersistentFlags().Set("token", clientOpts.Token)
-		if err != nil {
-			commands.CheckError(err)
-		}
+	commands.AddFileFlags(renderCmd, &fileOpts)
+	rootCmd.AddCommand(renderCmd)
+
+	// The api server, token and product are taken from flags, environment variables or the context of the config file.
+	rootCmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+		return commands.ResolveClientOptions(c, &clientOpts)
 	}
+	rootCmd.PersistentFlags().StringVarP(&clientOpts.Token, commands.FlagToken, "t", "", "Authentication token, defaults to $GIT_TOKEN or the token of the context")
+	rootCmd.PersistentFlags().StringVarP(&clientOpts.ServerAddr, commands.FlagAPIServer, "s", "", "URL to API server, defaults to $API_SERVER or the server of the context")
+	rootCmd.PersistentFlags().StringVar(&clientOpts.ConfigPath, "config", "", "Path to the config file, defaults to $NAUTES_CONFIG or ~/.nautes/config")
+	rootCmd.PersistentFlags().StringVar(&clientOpts.Context, "context", "", "Name of the context to use, defaults to $NAUTES_CONTEXT or the current context")
+
+	rootCmd.AddCommand(commands.NewConfigCommand(&clientOpts))
+	rootCmd.AddCommand(commands.NewClusterCommand(&clientOpts))
+	rootCmd.AddCommand(commands.NewPipelineRuntimeCommand(&clientOpts, applyResourceTypes))
 
 	// add get command for resource
+	var getOpts = commands.GetOptions{Out: os.Stdout}
 	var getCmd = &cobra.Command{
 		Use:   "get",
 		Short: "Get resources",
+		Long: `Get resources of one kind with its subcommand, or of several kinds separated by commas.
+The kind all is every kind of a product, with --all-products the kinds of a product are listed in every product.`,
+		Example: `nautes get env -p demo
+
+nautes get all -p demo
+
+nautes get env,cr,dr -p demo -o yaml
+
+nautes get all -A`,
 		Run: func(c *cobra.Command, args []string) {
-			if len(args) == 0 {
+			if len(args) != 1 {
 				c.HelpFunc()(c, args)
 				os.Exit(1)
 			}
+			getOpts.Kinds = args[0]
+			if getOpts.Product == "" && !getOpts.AllProducts {
+				getOpts.Product = clientOpts.Product
+			}
+			commands.CheckError(commands.GetResources(&clientOpts, &getOpts, applyResourceTypes))
 		},
 	}
+	getCmd.Flags().StringVarP(&getOpts.Output, "output", "o", "", "Output format. One of: json|yaml|wide|name|custom-columns=...|jsonpath=...|go-template=...|go-template-file=...")
+	getCmd.Flags().StringVar(&getOpts.Columns, "columns", "", "Columns added to the tables such as DESC:.git.gitlab.description, or removed from them such as -product, separated by commas")
+	getCmd.Flags().StringVar(&getOpts.SortBy, "sort-by", "", "Sort the resources of every kind by the values of a path of the yaml output, such as .name")
+	getCmd.Flags().StringVar(&getOpts.FieldSelector, "field-selector", "", "Keep the resources whose fields match, such as project=foo,envType!=prod, the fields are the paths of the yaml output")
+	getCmd.Flags().StringVar(&getOpts.NameRegex, "name-regex", "", "Keep the resources whose name matches the regular expression")
+	getCmd.Flags().StringVarP(&getOpts.Product, "product", "p", "", "Product of the resources, defaults to $PRODUCT or the product of the context")
+	getCmd.Flags().BoolVarP(&getOpts.AllProducts, "all-products", "A", false, "List the resources of the kinds of a product in every product")
 	for _, rc := range applyResourceTypes {
 		getCmd.AddCommand(commands.NewResourceCommand(&clientOpts, rc.ResourceType, rc.ResponseItemType, commands.SubGetCommand)...)
 	}
 	rootCmd.AddCommand(getCmd)
 
+	// add describe command for resource
+	var describeCmd = &cobra.Command{
+		Use:   "describe",
+		Short: "Show the details of a resource and the resources related to it",
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) == 0 {
+				c.HelpFunc()(c, args)
+				os.Exit(1)
+			}
+		},
+	}
+	for _, rc := range applyResourceTypes {
+		describeCmd.AddCommand(commands.NewResourceCommand(&clientOpts, rc.ResourceType, rc.ResponseItemType, commands.SubDescribeCommand(applyResourceTypes))...)
+	}
+	rootCmd.AddCommand(describeCmd)
+
 	// add delete command for resource
 	var deleteCmd = &cobra.Command{
 		Use:   "delete",
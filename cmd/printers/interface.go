@@ -21,6 +21,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 )
@@ -46,27 +47,141 @@ type mergeTo struct {
 type Columns struct {
 	PrintName string
 	FieldName string
+	// Priority above 0 shows the column only in the wide tables.
+	Priority int
+}
+
+// TableOptions changes the columns of a table generated from the column tags of the item type.
+type TableOptions struct {
+	// Wide includes the columns with a priority tag.
+	Wide bool
+	// Hidden are the names of the columns left out, the columns merged into a hidden column are shown on their own.
+	Hidden []string
+	// Prepended are the columns in front of the columns of the tags.
+	Prepended []ExtraColumn
+	// Appended are the columns behind the columns of the tags.
+	Appended []ExtraColumn
+}
+
+// ExtraColumn is a column which is not tagged on the item type, Value returns its value for the item of the index.
+type ExtraColumn struct {
+	Name  string
+	Value func(index int, value reflect.Value) (string, error)
 }
 
 // GenerateTable creates a metav1.Table structure from a slice of reflect.Values and the reflection type of the item.
 // It generates table columns and rows based on the provided data and returns the resulting metav1.Table.
 func GenerateTable(responseValues []reflect.Value, responseItemType reflect.Type) (*metav1.Table, error) {
+	return GenerateTableWithOptions(responseValues, responseItemType, TableOptions{})
+}
+
+// GenerateTableWithOptions creates the table like GenerateTable, with the columns changed by the options.
+// The values of the extra columns are in the first row of an item, the rows of its merged columns leave them empty.
+func GenerateTableWithOptions(responseValues []reflect.Value, responseItemType reflect.Type, options TableOptions) (*metav1.Table, error) {
 	columns, mergeTos := generateColumns(responseItemType)
+	columns, mergeTos, err := selectColumns(columns, mergeTos, options)
+	if err != nil {
+		return nil, err
+	}
 	//[Spec.Name Spec.Git.Gitlab.Name Spec.Git.Gitlab.Path Spec.Git.Gitlab.Visibility Spec.Git.Gitlab.Description]
 
 	//build columns to display
 	columnsDefinitions := buildPrintColumnsName(columns, mergeTos)
 
-	//build table rows
-	rows := buildTable(responseValues, columns, mergeTos)
+	if len(options.Prepended) == 0 && len(options.Appended) == 0 {
+		//build table rows
+		rows := buildTable(responseValues, columns, mergeTos)
+
+		table := &metav1.Table{
+			ColumnDefinitions: columnsDefinitions,
+			Rows:              rows,
+		}
+		return table, nil
+	}
 
-	table := &metav1.Table{
-		ColumnDefinitions: columnsDefinitions,
-		Rows:              rows,
+	table := &metav1.Table{}
+	for _, column := range options.Prepended {
+		table.ColumnDefinitions = append(table.ColumnDefinitions, metav1.TableColumnDefinition{Name: column.Name, Type: "string"})
+	}
+	table.ColumnDefinitions = append(table.ColumnDefinitions, columnsDefinitions...)
+	for _, column := range options.Appended {
+		table.ColumnDefinitions = append(table.ColumnDefinitions, metav1.TableColumnDefinition{Name: column.Name, Type: "string"})
+	}
+	for i, value := range responseValues {
+		prepended, err := buildExtraCells(options.Prepended, i, value)
+		if err != nil {
+			return nil, err
+		}
+		appended, err := buildExtraCells(options.Appended, i, value)
+		if err != nil {
+			return nil, err
+		}
+		for j, row := range buildTable([]reflect.Value{value}, columns, mergeTos) {
+			if j == 1 {
+				prepended, appended = make([]interface{}, len(prepended)), make([]interface{}, len(appended))
+			}
+			cells := append(append(append([]interface{}{}, prepended...), row.Cells...), appended...)
+			table.Rows = append(table.Rows, metav1.TableRow{Cells: cells})
+		}
 	}
 	return table, nil
 }
 
+// buildExtraCells returns the values of the extra columns for the item of the index.
+func buildExtraCells(extraColumns []ExtraColumn, index int, value reflect.Value) ([]interface{}, error) {
+	cells := make([]interface{}, 0, len(extraColumns))
+	for _, column := range extraColumns {
+		cell, err := column.Value(index, value)
+		if err != nil {
+			return nil, err
+		}
+		cells = append(cells, cell)
+	}
+	return cells, nil
+}
+
+// selectColumns removes the hidden columns, and the columns with a priority unless the table is wide.
+// The merges from and into a removed column are removed with it.
+func selectColumns(columns []*Columns, mergeTos []*mergeTo, options TableOptions) ([]*Columns, []*mergeTo, error) {
+	removed := make(map[string]bool)
+	for _, name := range options.Hidden {
+		var found bool
+		for _, column := range columns {
+			if strings.EqualFold(column.PrintName, name) {
+				removed[column.FieldName] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("unknown column %s", name)
+		}
+	}
+	if !options.Wide {
+		for _, column := range columns {
+			if column.Priority > 0 {
+				removed[column.FieldName] = true
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return columns, mergeTos, nil
+	}
+
+	var selectedColumns []*Columns
+	for _, column := range columns {
+		if !removed[column.FieldName] {
+			selectedColumns = append(selectedColumns, column)
+		}
+	}
+	var selectedMergeTos []*mergeTo
+	for _, val := range mergeTos {
+		if !removed[val.from] && !removed[val.to] {
+			selectedMergeTos = append(selectedMergeTos, val)
+		}
+	}
+	return selectedColumns, selectedMergeTos, nil
+}
+
 // generateColumns extracts Columns and mergeTo specifications from the reflection type of the resource.
 // It returns the list of Columns and mergeTo specifications for further processing.
 func generateColumns(resourceType reflect.Type) (columns []*Columns, mergeTos []*mergeTo) {
@@ -94,6 +209,7 @@ func findDeepField(sType reflect.Type) (columns []*Columns, mergeTos []*mergeTo)
 		var fieldName = field.Name
 		column := field.Tag.Get(types.Column)
 		mergeToColumn := field.Tag.Get(types.MergeTo)
+		priority, _ := strconv.Atoi(field.Tag.Get(types.Priority))
 		if kind == reflect.Struct {
 			if column != "" {
 				if strings.Contains(column, ":") {
@@ -103,7 +219,7 @@ func findDeepField(sType reflect.Type) (columns []*Columns, mergeTos []*mergeTo)
 						fieldName = fmt.Sprintf("%s:%s", fieldName, columnArr[1])
 					}
 				}
-				columns, mergeTos = dealTagForColumns(column, mergeToColumn, fieldName, columns, mergeTos)
+				columns, mergeTos = dealTagForColumns(column, mergeToColumn, fieldName, priority, columns, mergeTos)
 				continue
 			}
 		}
@@ -123,12 +239,12 @@ func findDeepField(sType reflect.Type) (columns []*Columns, mergeTos []*mergeTo)
 			case reflect.Struct:
 				columns, mergeTos = addPrefixFieldNameToDeepField(fieldType, fieldName, columns, mergeTos)
 			default:
-				columns, mergeTos = dealTagForColumns(column, mergeToColumn, fieldName, columns, mergeTos)
+				columns, mergeTos = dealTagForColumns(column, mergeToColumn, fieldName, priority, columns, mergeTos)
 			}
 		case reflect.Map:
 			continue
 		default:
-			columns, mergeTos = dealTagForColumns(column, mergeToColumn, fieldName, columns, mergeTos)
+			columns, mergeTos = dealTagForColumns(column, mergeToColumn, fieldName, priority, columns, mergeTos)
 		}
 	}
 	return
@@ -151,11 +267,12 @@ func addPrefixFieldNameToDeepField(fieldType reflect.Type, fieldName string, col
 }
 
 // dealTagForColumns processes the print and mergeTo tags for a field and updates the corresponding lists.
-func dealTagForColumns(printColumn, mergeToColumn, fieldName string, columns []*Columns, mergeTos []*mergeTo) ([]*Columns, []*mergeTo) {
+func dealTagForColumns(printColumn, mergeToColumn, fieldName string, priority int, columns []*Columns, mergeTos []*mergeTo) ([]*Columns, []*mergeTo) {
 	if printColumn != "" {
 		columns = append(columns, &Columns{
 			PrintName: printColumn,
 			FieldName: fieldName,
+			Priority:  priority,
 		})
 	}
 	if mergeToColumn != "" {
@@ -15,6 +15,8 @@
 package types
 
 import (
+	"gopkg.in/yaml.v3"
+	"io"
 	"reflect"
 )
 
@@ -35,9 +37,12 @@ const (
 	RemoveOrder  = "removeOrder"
 	Column       = "column"
 	MergeTo      = "mergeTo"
+	Ref          = "ref"
+	RefProduct   = "refProduct"
+	Priority     = "priority"
 )
 
-type ResourceFunc func(apiServer string, token string, skipCheck bool, resource string, resourceHandler ResourceHandler) error
+type ResourceFunc func(apiServer string, token string, skipCheck bool, resource *yaml.Node, resourceHandler ResourceHandler, out io.Writer) error
 
 type ResourcesType struct {
 	ResourceType     reflect.Type
@@ -108,10 +113,13 @@ type ClusterResponseItem struct {
 	Usage         string   `yaml:"usage" json:"usage" column:"Usage" mergeTo:"ApiServer"`
 	ClusterType   string   `yaml:"clusterType" json:"cluster_type" column:"CT"  mergeTo:"ApiServer"`
 	WorkerType    string   `yaml:"workerType" json:"worker_type" column:"WT" mergeTo:"ApiServer"`
-	HostCluster   string   `yaml:"hostCluster" json:"host_cluster"`
+	HostCluster   string   `yaml:"hostCluster" json:"host_cluster" column:"HostCluster" priority:"1" ref:"Cluster"`
 	PrimaryDomain string   `yaml:"primaryDomain" json:"primary_domain" column:"PrimaryDomain"`
 	Kubeconfig    string   `yaml:"kubeconfig" json:"kubeconfig"`
 	VCluster      VCluster `yaml:"vcluster" json:"vcluster"`
+	// +optional
+	// KubeconfigFrom is where the CLI loads the kubeconfig from instead of Kubeconfig, it is resolved before the cluster is sent to the API server.
+	KubeconfigFrom *KubeconfigSource `yaml:"kubeconfigFrom,omitempty" json:"-"`
 	// ReservedNamespacesAllowedProducts key is namespace name, value is the product name list witch can use namespace.
 	ReservedNamespacesAllowedProducts map[string][]string `yaml:"reservedNamespacesAllowedProducts" json:"reserved_namespaces_allowed_products"`
 	// +optional
@@ -120,13 +128,27 @@ type ClusterResponseItem struct {
 	ComponentsList                 ComponentsList                   `yaml:"componentsList" json:"components_list" column:"ComponentsList:Name"`
 }
 
+// KubeconfigSource declares where the kubeconfig of a cluster is loaded from, one of File, Env and Exec.
+// With only Context set, the kubeconfig is loaded from $KUBECONFIG or ~/.kube/config.
+type KubeconfigSource struct {
+	// File is the path of a kubeconfig file.
+	File string `yaml:"file" json:"file"`
+	// Env is the name of the environment variable holding the kubeconfig.
+	Env string `yaml:"env" json:"env"`
+	// Exec is a shell command printing the kubeconfig to stdout.
+	Exec string `yaml:"exec" json:"exec"`
+	// +optional
+	// Context is the context of the kubeconfig to keep, the kubeconfig is reduced to this context with its credentials embedded.
+	Context string `yaml:"context" json:"context"`
+}
+
 type ClusterResourceInfo struct {
 	Kind  string `yaml:"kind" json:"kind"`
 	Group string `yaml:"group" json:"group"`
 }
 
 type VCluster struct {
-	HTTPSNodePort string `yaml:"httpsNodePort" json:"https_node_port"`
+	HTTPSNodePort string `yaml:"httpsNodePort" json:"https_node_port" column:"HttpsNodePort" priority:"1"`
 }
 
 // ComponentsList declares the specific components used by the cluster
@@ -216,8 +238,8 @@ type EnvironmentResponse struct {
 
 type EnvironmentResponseItem struct {
 	Name    string `yaml:"name" json:"name" column:"name"`
-	Product string `yaml:"product" json:"product" column:"product"`
-	Cluster string `yaml:"cluster" json:"cluster" column:"cluster"`
+	Product string `yaml:"product" json:"product" column:"product" ref:"Product"`
+	Cluster string `yaml:"cluster" json:"cluster" column:"cluster" ref:"Cluster"`
 	EnvType string `yaml:"envType" json:"env_type" column:"env_type"`
 }
 
@@ -245,7 +267,7 @@ type ProjectResponse struct {
 
 type ProjectResponseItem struct {
 	Name     string `yaml:"name" json:"name" column:"name"`
-	Product  string `yaml:"product" json:"product" column:"product"`
+	Product  string `yaml:"product" json:"product" column:"product" ref:"Product"`
 	Language string `yaml:"language" json:"language" column:"language"`
 }
 
@@ -273,12 +295,12 @@ type CodeRepoResponse struct {
 
 type CodeRepoResponseItem struct {
 	Name                   string                       `yaml:"name" json:"name" column:"name"`
-	Product                string                       `yaml:"product" json:"product" column:"product"`
-	Project                string                       `yaml:"project" json:"project" column:"project" mergeTo:"product"`
+	Product                string                       `yaml:"product" json:"product" column:"product" ref:"Product"`
+	Project                string                       `yaml:"project" json:"project" column:"project" mergeTo:"product" ref:"Project"`
 	Git                    *CodeRepoResponseItemGit     `yaml:"git" json:"git"`
 	Webhook                *CodeRepoResponseItemWebhook `yaml:"webhook" json:"webhook"`
-	DeploymentRuntime      bool                         `yaml:"deploymentRuntime" json:"deployment_runtime"`
-	ProjectPipelineRuntime bool                         `yaml:"projectPipelineRuntime" json:"pipeline_runtime"`
+	DeploymentRuntime      bool                         `yaml:"deploymentRuntime" json:"deployment_runtime" column:"DeploymentRuntime" priority:"1"`
+	ProjectPipelineRuntime bool                         `yaml:"projectPipelineRuntime" json:"pipeline_runtime" column:"PipelineRuntime" priority:"1"`
 }
 
 type CodeRepoResponseItemGit struct {
@@ -293,7 +315,7 @@ type CodeRepoGitRepoDetails struct {
 	Name          string `yaml:"name" json:"name"`
 	Path          string `yaml:"path" json:"path" column:"path"`
 	Visibility    string `yaml:"visibility" json:"visibility" column:"visibility" mergeTo:"path"`
-	Description   string `yaml:"description" json:"description"`
+	Description   string `yaml:"description" json:"description" column:"description" priority:"1"`
 	SshUrlToRepo  string `yaml:"sshUrlToRepo" json:"ssh_url_to_repo" column:"ssh_url_to_repo"`
 	HttpUrlToRepo string `yaml:"httpUrlToRepo" json:"http_url_to_repo" column:"http_url_to_repo" mergeTo:"ssh_url_to_repo"`
 }
@@ -322,11 +344,11 @@ type CodeRepoBindingResponse struct {
 
 type CodeRepoBindingResponseItem struct {
 	Name        string   `yaml:"name" json:"name" column:"name"`
-	ProductName string   `yaml:"productName" json:"product_name"`
-	Product     string   `yaml:"product" json:"product" column:"product"`
-	CodeRepo    string   `yaml:"coderepo" json:"coderepo" column:"coderepo"`
+	ProductName string   `yaml:"productName" json:"product_name" ref:"Product"`
+	Product     string   `yaml:"product" json:"product" column:"product" ref:"Product"`
+	CodeRepo    string   `yaml:"coderepo" json:"coderepo" column:"coderepo" ref:"CodeRepo"`
 	Permissions string   `yaml:"permissions" json:"permissions" column:"permissions"`
-	Projects    []string `yaml:"projects" json:"projects" column:"projects"`
+	Projects    []string `yaml:"projects" json:"projects" column:"projects" ref:"Project" refProduct:"Product"`
 }
 
 func (c *CodeRepoBinding) GetKind() string {
@@ -354,7 +376,7 @@ type ProjectPipelineRuntimeCommonPipelines struct {
 }
 
 type ProjectPipelineRuntimeCommonDestination struct {
-	Environment string `yaml:"environment" json:"environment" column:"environment"`
+	Environment string `yaml:"environment" json:"environment" column:"environment" ref:"Environment"`
 	Namespace   string `yaml:"namespace" json:"namespace" column:"namespace" mergeTo:"environment"`
 }
 
@@ -367,7 +389,7 @@ type ProjectPipelineRuntimeAdditionalResources struct {
 // ProjectPipelineRuntimeAdditionalResourcesGit defines the additional resources if it comes from git
 type ProjectPipelineRuntimeAdditionalResourcesGit struct {
 	// Optional
-	CodeRepo string `yaml:"codeRepo" json:"coderepo"`
+	CodeRepo string `yaml:"codeRepo" json:"coderepo" ref:"CodeRepo"`
 	// Optional
 	// If git repo is a public repo, use url instead
 	URL      string `yaml:"url" json:"url"`
@@ -382,13 +404,13 @@ type ProjectPipelineRuntimeResponse struct {
 type ProjectPipelineRuntimeResponseItem struct {
 	Name        string                                   `yaml:"name" json:"name" column:"name"`
 	Account     string                                   `yaml:"account" json:"account" column:"account"  mergeTo:"name"`
-	Project     string                                   `yaml:"project" json:"project" column:"project"`
+	Project     string                                   `yaml:"project" json:"project" column:"project" ref:"Project"`
 	Destination *ProjectPipelineRuntimeCommonDestination `yaml:"destination" json:"destination"`
-	Isolation   string                                   `yaml:"isolation" json:"isolation"`
+	Isolation   string                                   `yaml:"isolation" json:"isolation" column:"isolation" priority:"1"`
 	Pipelines   *[]ProjectPipelineRuntimeCommonPipelines `yaml:"pipelines" json:"pipelines"`
 	// Optional
-	Product          string                                              `yaml:"product" json:"product"`
-	PipelineSource   string                                              `yaml:"pipelineSource" json:"pipeline_source" column:"PipelineSource"`
+	Product          string                                              `yaml:"product" json:"product" column:"product" priority:"1" ref:"Product"`
+	PipelineSource   string                                              `yaml:"pipelineSource" json:"pipeline_source" column:"PipelineSource" ref:"CodeRepo"`
 	EventSources     *[]ProjectPipelineRuntimeResponseItemEventSources   `yaml:"eventSources" json:"event_sources"`
 	PipelineTriggers *ProjectPipelineRuntimeResponseItemPipelineTriggers `yaml:"pipelineTriggers" json:"pipeline_triggers"`
 	// +optional
@@ -430,7 +452,7 @@ type ProjectPipelineRuntimeResponseItemEventSources struct {
 }
 
 type ProjectPipelineRuntimeResponseItemEventSourcesGitlab struct {
-	RepoName string   `yaml:"repoName" json:"repo_name"  column:"RepoName"`
+	RepoName string   `yaml:"repoName" json:"repo_name"  column:"RepoName" ref:"CodeRepo"`
 	Revision string   `yaml:"revision" json:"revision"`
 	Events   []string `yaml:"events" json:"events"`
 }
@@ -499,20 +521,20 @@ type DeploymentRuntimeResponse struct {
 type DeploymentRuntimeResponseItem struct {
 	Name           string                                       `yaml:"name" json:"name" column:"name"`
 	Account        string                                       `yaml:"account" json:"account" column:"account"  mergeTo:"name"`
-	Product        string                                       `yaml:"product" json:"product" column:"product"`
+	Product        string                                       `yaml:"product" json:"product" column:"product" ref:"Product"`
 	ManifestSource *DeploymentRuntimeResponseItemManifestSource `yaml:"manifestsource" json:"manifest_source"`
-	ProjectsRef    []string                                     `yaml:"projectsRef" json:"projects_ref" column:"projectsRef"`
+	ProjectsRef    []string                                     `yaml:"projectsRef" json:"projects_ref" column:"projectsRef" ref:"Project"`
 	Destination    *DeploymentRuntimeResponseItemDestination    `yaml:"destination" json:"destination"`
 }
 
 type DeploymentRuntimeResponseItemManifestSource struct {
-	CodeRepo       string `yaml:"codeRepo" json:"code_repo" column:"codeRepo"`
+	CodeRepo       string `yaml:"codeRepo" json:"code_repo" column:"codeRepo" ref:"CodeRepo"`
 	TargetRevision string `yaml:"targetRevision" json:"target_revision" column:"targetRevision" mergeTo:"codeRepo"`
 	Path           string `yaml:"path" json:"path" column:"path" mergeTo:"codeRepo"`
 }
 
 type DeploymentRuntimeResponseItemDestination struct {
-	Environment string   `yaml:"environment" json:"environment" column:"environment"`
+	Environment string   `yaml:"environment" json:"environment" column:"environment" ref:"Environment"`
 	Namespaces  []string `yaml:"namespaces" json:"namespaces" column:"namespaces" mergeTo:"environment"`
 }
 
@@ -533,4 +555,10 @@ type ClientOptions struct {
 	ServerAddr string
 	Token      string
 	SkipCheck  bool
+	// Product is the default product of product-scoped commands, taken from PRODUCT or the context.
+	Product string
+	// ConfigPath is the path of the config file holding the contexts.
+	ConfigPath string
+	// Context is the name of the context to use instead of the current context.
+	Context string
 }